@@ -5,61 +5,298 @@
 //!+
 
 // Modify dup2 to print the names of all files in which each duplicated line occurs.
+//
+// With -format grep, every occurrence is printed as file:line, and with
+// -format json, the occurrences of each line are listed in JSON. Directories
+// are counted recursively, see walk, and binary files are skipped. Files are
+// read by -workers goroutines in parallel, see countFiles. With -lowmem, only
+// hashes of the lines are kept, see countLowMem.
 package main
 
 import (
     "bufio"
+    "encoding/json"
+    "flag"
     "fmt"
+    "io"
     "os"
+    "runtime"
+    "sort"
     "strings"
 )
 
 type set map[string]bool
 
+var (
+    sortBy = flag.String("sort", "count",
+        "Order of the duplicated lines: count (most frequent first), line (lexicographic) or first (first occurrence)")
+    top    = flag.Int("top", 0, "Print only the first N duplicated lines, all if 0")
+    format = flag.String("format", "summary",
+        "Output format: summary (count, line and files), grep (file:line: text per occurrence) or json")
+    workers = flag.Int("workers", runtime.NumCPU(), "Number of files read in parallel")
+)
+
+// A location is where a line occurs.
+type location struct {
+    File string `json:"file"`
+    Line int    `json:"line"`
+    seq  int    // position of File among the files counted
+}
+
+// before reports whether l comes before m in the order the files were found.
+func (l location) before(m location) bool {
+    if l.seq != m.seq {
+        return l.seq < m.seq
+    }
+    return l.Line < m.Line
+}
+
+func (l location) String() string { return fmt.Sprintf("%s:%d", l.File, l.Line) }
+
+// A counter counts lines and records where they occur.
+type counter struct {
+    counts map[string]int
+    locs   map[string][]location
+}
+
+func newCounter() *counter {
+    return &counter{counts: make(map[string]int), locs: make(map[string][]location)}
+}
+
+// A dup is a duplicated line to print, with the sorted names of the files it
+// occurs in.
+type dup struct {
+    line        string
+    count       int
+    files       []string
+    occurrences func(visit func(loc location) error) error // in order
+}
+
+// A dupsFunc calls visit with the duplicated lines to print, in order.
+type dupsFunc func(visit func(d dup) error) error
+
 func main() {
-    counts := make(map[string]int)
-    dupFiles := make(map[string]set)
-    files := os.Args[1:]
-    if len(files) == 0 {
-        countLines(os.Stdin, counts, dupFiles)
+    flag.Parse()
+    switch *sortBy {
+    case "count", "line", "first":
+    default:
+        fmt.Fprintf(os.Stderr, "dup2: -sort must be count, line or first, not %q\n", *sortBy)
+        os.Exit(1)
+    }
+    switch *format {
+    case "summary", "grep", "json":
+    default:
+        fmt.Fprintf(os.Stderr, "dup2: -format must be summary, grep or json, not %q\n", *format)
+        os.Exit(1)
+    }
+    if *workers < 1 {
+        fmt.Fprintf(os.Stderr, "dup2: -workers must be at least 1, not %d\n", *workers)
+        os.Exit(1)
+    }
+    if *memBudget < 1 {
+        fmt.Fprintf(os.Stderr, "dup2: -mem must be at least 1, not %d\n", *memBudget)
+        os.Exit(1)
+    }
+    if err := printDuplicates(os.Stdout, flag.Args()); err != nil {
+        fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+        os.Exit(1)
+    }
+}
+
+// printDuplicates counts the lines of the files named or found below names,
+// or of the standard input if there are none, and prints the duplicated ones
+// to out as the flags say.
+func printDuplicates(out io.Writer, names []string) error {
+    if *lowMem {
+        lm, err := countLowMem(names, *workers)
+        if err != nil {
+            return err
+        }
+        defer lm.close()
+        return printDups(out, lm.duplicates(*top), *format)
+    }
+    c := newCounter()
+    if len(names) == 0 {
+        if err := c.countLines(os.Stdin, os.Stdin.Name(), 0); err != nil {
+            return err
+        }
     } else {
-        for _, arg := range files {
-            f, err := os.Open(arg)
-            if err != nil {
-                fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
-                continue
+        var err error
+        if c, err = countFiles(names, *workers); err != nil {
+            return err
+        }
+    }
+    dups := c.duplicates(*sortBy)
+    if *top > 0 && len(dups) > *top {
+        dups = dups[:*top]
+    }
+    return printDups(out, c.each(dups), *format)
+}
+
+// printDups prints the duplicated lines of dups to out in the given format.
+func printDups(out io.Writer, dups dupsFunc, format string) error {
+    w := bufio.NewWriter(out)
+    var err error
+    switch format {
+    case "grep":
+        // One occurrence per line, as compilers and grep -n report them,
+        // so that editors can jump to each.
+        err = dups(func(d dup) error {
+            return d.occurrences(func(loc location) error {
+                _, err := fmt.Fprintf(w, "%s: %s\n", loc, d.line)
+                return err
+            })
+        })
+    case "json":
+        // The occurrences are streamed, so the JSON is written by hand in
+        // the layout of json.Encoder.SetIndent("", "  ").
+        n := 0
+        err = dups(func(d dup) error {
+            if n == 0 {
+                fmt.Fprint(w, "[\n")
+            } else {
+                fmt.Fprint(w, ",\n")
             }
-            countLines(f, counts, dupFiles)
-            f.Close()
+            n++
+            fmt.Fprintf(w, "  {\n    \"line\": %s,\n    \"count\": %d,\n    \"occurrences\": [\n",
+                jsonString(d.line), d.count)
+            i := 0
+            err := d.occurrences(func(loc location) error {
+                if i > 0 {
+                    fmt.Fprint(w, ",\n")
+                }
+                i++
+                _, err := fmt.Fprintf(w, "      {\n        \"file\": %s,\n        \"line\": %d\n      }",
+                    jsonString(loc.File), loc.Line)
+                return err
+            })
+            fmt.Fprint(w, "\n    ]\n  }")
+            return err
+        })
+        if n == 0 {
+            fmt.Fprint(w, "[]\n")
+        } else {
+            fmt.Fprint(w, "\n]\n")
         }
+    default:
+        err = dups(func(d dup) error {
+            _, err := fmt.Fprintf(w, "%d\t%s\t%s\n", d.count, d.line, strings.Join(d.files, ","))
+            return err
+        })
     }
-    for line, n := range counts {
-        if n > 1 {
-            filesSet := dupFiles[line]
-            fileNames := make([]string, 0, len(filesSet))
-            for f := range filesSet {
-                //fmt.Println("hoho: ", f)
-                fileNames = append(fileNames, f)
+    if ferr := w.Flush(); err == nil {
+        err = ferr
+    }
+    return err
+}
+
+// jsonString returns s quoted as a JSON string.
+func jsonString(s string) string {
+    b, _ := json.Marshal(s)
+    return string(b)
+}
+
+// each returns the duplicated lines dups of c to print.
+func (c *counter) each(dups []string) dupsFunc {
+    return func(visit func(d dup) error) error {
+        for _, line := range dups {
+            locs := c.locs[line]
+            d := dup{line, c.counts[line], c.files(line), func(visit func(loc location) error) error {
+                for _, loc := range locs {
+                    if err := visit(loc); err != nil {
+                        return err
+                    }
+                }
+                return nil
+            }}
+            if err := visit(d); err != nil {
+                return err
             }
-            fmt.Printf("%d\t%s\t%s\n", n, line, strings.Join(fileNames, ","))
         }
+        return nil
     }
 }
 
-func countLines(f *os.File, counts map[string]int, dup_files map[string]set) {
-    input := bufio.NewScanner(f)
-    for input.Scan() {
-        s := input.Text()
-        counts[s]++
-        if val, ok := dup_files[s]; ok {
-            val[f.Name()] = true
-        } else {
-            val = make(set)
-            val[f.Name()] = true
-            dup_files[s] = val
+// files returns the sorted names of the files in which line occurs.
+func (c *counter) files(line string) []string {
+    seen := make(set)
+    var fileNames []string
+    for _, loc := range c.locs[line] {
+        if !seen[loc.File] {
+            seen[loc.File] = true
+            fileNames = append(fileNames, loc.File)
+        }
+    }
+    sort.Strings(fileNames)
+    return fileNames
+}
+
+// duplicates returns the lines occurring more than once, ordered by key:
+// "count" puts the most frequent lines first and breaks ties by line, "line"
+// orders lines lexicographically and "first" by their first occurrence.
+func (c *counter) duplicates(key string) []string {
+    var dups []string
+    for line, n := range c.counts {
+        if n > 1 {
+            dups = append(dups, line)
         }
     }
-    // NOTE: ignoring potential errors from input.Err()
+    switch key {
+    case "count":
+        sort.Slice(dups, func(i, j int) bool {
+            if ni, nj := c.counts[dups[i]], c.counts[dups[j]]; ni != nj {
+                return ni > nj
+            }
+            return dups[i] < dups[j]
+        })
+    case "line":
+        sort.Strings(dups)
+    case "first":
+        sort.Slice(dups, func(i, j int) bool {
+            return c.locs[dups[i]][0].before(c.locs[dups[j]][0])
+        })
+    }
+    return dups
+}
+
+// countFile counts the lines of the file name, the seq-th counted, unless it
+// is binary. A file that can't be opened is reported and skipped, but a
+// failed read is an error.
+func (c *counter) countFile(name string, seq int) error {
+    f, err := os.Open(name)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+        return nil
+    }
+    defer f.Close()
+    r := bufio.NewReaderSize(f, sniffLen)
+    if isBinary(r) {
+        return nil
+    }
+    return c.countLines(r, name, seq)
+}
+
+// maxLine is the length of the longest line read, beyond which reading fails.
+const maxLine = 64 << 20
+
+// newScanner returns a Scanner of the lines of r up to maxLine long.
+func newScanner(r io.Reader) *bufio.Scanner {
+    input := bufio.NewScanner(r)
+    input.Buffer(nil, maxLine)
+    return input
+}
+
+func (c *counter) countLines(r io.Reader, name string, seq int) error {
+    input := newScanner(r)
+    for n := 1; input.Scan(); n++ {
+        s := input.Text()
+        c.counts[s]++
+        c.locs[s] = append(c.locs[s], location{name, n, seq})
+    }
+    if err := input.Err(); err != nil {
+        return fmt.Errorf("reading %s: %v", name, err)
+    }
+    return nil
 }
 
 //!-
@@ -0,0 +1,154 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "bytes"
+    "fmt"
+    "hash"
+    "hash/fnv"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// writeFiles writes n files of lines lines each below a temporary directory,
+// some in a subdirectory, and returns the directory. Lines repeat within and
+// across files, with some empty or unique.
+func writeFiles(t *testing.T, n, lines int) string {
+    dir := t.TempDir()
+    if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < n; i++ {
+        var b bytes.Buffer
+        for j := 0; j < lines; j++ {
+            switch k := (i*lines + j) * 7919 % 20011; {
+            case k%17 == 0:
+                fmt.Fprintln(&b)
+            case k%5 == 0:
+                fmt.Fprintf(&b, "unique %d %d\n", i, j)
+            default:
+                fmt.Fprintf(&b, "line %d\n", k%4000)
+            }
+        }
+        name := fmt.Sprintf("f%d.txt", i)
+        if i%3 == 0 {
+            name = filepath.Join("sub", name)
+        }
+        if err := os.WriteFile(filepath.Join(dir, name), b.Bytes(), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return dir
+}
+
+// options are the flags of a run of dup2.
+type options struct {
+    lowMem  bool
+    mem     int
+    workers int
+    format  string
+    sortBy  string
+    top     int
+}
+
+// dups returns what dup2 prints for the files below dir with the flags o,
+// which are restored afterwards.
+func dups(t *testing.T, dir string, o options) string {
+    defer func(saved options) {
+        *lowMem, *memBudget, *workers, *format, *sortBy, *top = saved.lowMem, saved.mem, saved.workers,
+            saved.format, saved.sortBy, saved.top
+    }(options{*lowMem, *memBudget, *workers, *format, *sortBy, *top})
+    if o.mem == 0 {
+        o.mem = 256
+    }
+    *lowMem, *memBudget, *workers, *format, *sortBy, *top = o.lowMem, o.mem, o.workers, o.format, o.sortBy, o.top
+    var out bytes.Buffer
+    if err := printDuplicates(&out, []string{dir}); err != nil {
+        t.Fatalf("%+v: %v", o, err)
+    }
+    return out.String()
+}
+
+func TestSummary(t *testing.T) {
+    dir := t.TempDir()
+    os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\nb\na\n"), 0644)
+    os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\nc\n"), 0644)
+    for _, lm := range []bool{false, true} {
+        got := dups(t, dir, options{lowMem: lm, workers: 1, format: "summary", sortBy: "line"})
+        want := fmt.Sprintf("2\ta\t%[1]s/a.txt\n2\tb\t%[1]s/a.txt,%[1]s/b.txt\n", dir)
+        if got != want {
+            t.Errorf("lowmem %t: got %q, want %q", lm, got, want)
+        }
+    }
+}
+
+func TestWorkers(t *testing.T) {
+    dir := writeFiles(t, 12, 500)
+    for _, format := range []string{"summary", "grep", "json"} {
+        for _, sortBy := range []string{"count", "line", "first"} {
+            o := options{workers: 1, format: format, sortBy: sortBy}
+            want := dups(t, dir, o)
+            for _, n := range []int{2, 5, 16} {
+                o.workers = n
+                if got := dups(t, dir, o); got != want {
+                    t.Errorf("-format %s -sort %s: output of %d workers differs from 1", format, sortBy, n)
+                }
+            }
+        }
+    }
+}
+
+func TestLowMem(t *testing.T) {
+    const files, lines = 12, 6000
+    // With -mem 1, the records of a worker take more runs than are merged
+    // at once, so that runs are merged in several passes.
+    b := newBudget(1)
+    if files*lines*recordMem <= b.sortMem*b.fanIn {
+        t.Fatalf("%d lines fit in %d runs", files*lines, b.fanIn)
+    }
+    dir := writeFiles(t, files, lines)
+    for _, format := range []string{"summary", "grep", "json"} {
+        for _, sortBy := range []string{"count", "line", "first"} {
+            for _, top := range []int{0, 7} {
+                o := options{workers: 4, format: format, sortBy: sortBy, top: top}
+                want := dups(t, dir, o)
+                o.lowMem, o.mem = true, 1
+                for _, n := range []int{1, 3} {
+                    o.workers = n
+                    if got := dups(t, dir, o); got != want {
+                        t.Errorf("-format %s -sort %s -top %d -workers %d: -lowmem output differs", format,
+                            sortBy, top, n)
+                    }
+                }
+            }
+        }
+    }
+}
+
+// A collider makes the lines collide into 3 hashes.
+type collider struct{ hash.Hash64 }
+
+func (c collider) Sum64() uint64 { return c.Hash64.Sum64() % 3 }
+
+func TestLowMemCollisions(t *testing.T) {
+    defer func(saved func() hash.Hash64) { newHash = saved }(newHash)
+    newHash = func() hash.Hash64 { return collider{fnv.New64a()} }
+    dir := writeFiles(t, 6, 2000)
+    for _, format := range []string{"summary", "grep", "json"} {
+        for _, sortBy := range []string{"count", "line", "first"} {
+            o := options{workers: 2, format: format, sortBy: sortBy}
+            want := dups(t, dir, o)
+            o.lowMem, o.mem = true, 1
+            if got := dups(t, dir, o); got != want {
+                t.Errorf("-format %s -sort %s: -lowmem output differs with colliding hashes", format, sortBy)
+            }
+        }
+    }
+}
+
+//!-
@@ -0,0 +1,3 @@
+module dup2
+
+go 1.19
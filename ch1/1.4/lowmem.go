@@ -0,0 +1,763 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "container/heap"
+    "encoding/binary"
+    "errors"
+    "flag"
+    "fmt"
+    "hash/fnv"
+    "io"
+    "os"
+    "sort"
+)
+
+var (
+    lowMem    = flag.Bool("lowmem", false, "Keep hashes of the lines instead of the lines, spilling them to disk beyond -mem")
+    memBudget = flag.Int("mem", 256, "Memory budget in MiB of the hashes and merge buffers of -lowmem")
+)
+
+// newHash returns the hash of the lines in -lowmem mode. Tests replace it to
+// make lines collide.
+var newHash = fnv.New64a
+
+// A record is an occurrence of a line in -lowmem mode: the hash of the line
+// and where to read it again, the line being kept on disk only. Records are
+// also used to order the duplicated lines, by a hash and key standing for
+// the -sort order.
+type record struct {
+    hash   uint64
+    key    []byte // compared after hash, if set
+    seq    uint32 // position of the file among the files counted
+    length uint32 // length of the line in bytes
+    line   uint64 // line number
+    offset uint64 // offset of the line in the file
+}
+
+// recordSize is the size of a record on disk, followed by its key, and
+// recordMem about its size in memory, without its key.
+const (
+    recordSize = 36
+    recordMem  = 64
+)
+
+func (r record) less(s record) bool {
+    if r.hash != s.hash {
+        return r.hash < s.hash
+    }
+    if c := bytes.Compare(r.key, s.key); c != 0 {
+        return c < 0
+    }
+    if r.seq != s.seq {
+        return r.seq < s.seq
+    }
+    return r.line < s.line
+}
+
+// writeRecord writes r to w in the format read by readRecord.
+func writeRecord(w *bufio.Writer, r record) error {
+    var buf [recordSize]byte
+    binary.LittleEndian.PutUint64(buf[0:], r.hash)
+    binary.LittleEndian.PutUint32(buf[8:], r.seq)
+    binary.LittleEndian.PutUint32(buf[12:], r.length)
+    binary.LittleEndian.PutUint64(buf[16:], r.line)
+    binary.LittleEndian.PutUint64(buf[24:], r.offset)
+    binary.LittleEndian.PutUint32(buf[32:], uint32(len(r.key)))
+    w.Write(buf[:])
+    _, err := w.Write(r.key)
+    return err
+}
+
+// readRecord reads the next record from r, reporting false at its end.
+func readRecord(r *bufio.Reader) (record, bool) {
+    var buf [recordSize]byte
+    if _, err := io.ReadFull(r, buf[:]); err != nil {
+        return record{}, false
+    }
+    rec := record{
+        hash:   binary.LittleEndian.Uint64(buf[0:]),
+        seq:    binary.LittleEndian.Uint32(buf[8:]),
+        length: binary.LittleEndian.Uint32(buf[12:]),
+        line:   binary.LittleEndian.Uint64(buf[16:]),
+        offset: binary.LittleEndian.Uint64(buf[24:]),
+    }
+    if n := binary.LittleEndian.Uint32(buf[32:]); n > 0 {
+        rec.key = make([]byte, n)
+        if _, err := io.ReadFull(r, rec.key); err != nil {
+            return record{}, false
+        }
+    }
+    return rec, true
+}
+
+// runBuffer is the size of the buffer of each run read or written.
+const runBuffer = 64 << 10
+
+// maxFanIn is the largest number of runs merged at once, which keeps the
+// number of open files well below the usual limit of 1024.
+const maxFanIn = 256
+
+// A budget divides the -mem budget between the records sorted in memory and
+// the buffers of the runs merged, half each.
+type budget struct {
+    sortMem int // bytes of records kept in memory
+    fanIn   int // number of runs merged at once
+}
+
+func newBudget(mem int) budget {
+    half := mem << 20 / 2
+    b := budget{sortMem: half, fanIn: half/runBuffer - 2} // and two buffers to write
+    if b.fanIn > maxFanIn {
+        b.fanIn = maxFanIn
+    }
+    if b.fanIn < 2 {
+        b.fanIn = 2
+    }
+    return b
+}
+
+// A sorter collects records, spilling them to disk in sorted runs whenever
+// they take max bytes, which are merged by mergeRuns. The runs are closed
+// once written, and named by their temporary files.
+type sorter struct {
+    max  int
+    size int // bytes taken by recs
+    recs []record
+    runs []string
+}
+
+func (s *sorter) add(r record) error {
+    s.recs = append(s.recs, r)
+    s.size += recordMem + len(r.key)
+    if s.size < s.max {
+        return nil
+    }
+    return s.spill()
+}
+
+// spill writes the records of s sorted to a temporary file.
+func (s *sorter) spill() error {
+    sort.Slice(s.recs, func(i, j int) bool { return s.recs[i].less(s.recs[j]) })
+    f, err := os.CreateTemp("", "dup-*")
+    if err != nil {
+        return err
+    }
+    s.runs = append(s.runs, f.Name())
+    w := bufio.NewWriterSize(f, runBuffer)
+    for i, r := range s.recs {
+        writeRecord(w, r)
+        s.recs[i] = record{} // drop the key
+    }
+    s.recs, s.size = s.recs[:0], 0
+    err = w.Flush()
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    return err
+}
+
+// finish spills the records left in s and returns its runs, which are then
+// owned by the caller.
+func (s *sorter) finish() ([]string, error) {
+    if len(s.recs) > 0 {
+        if err := s.spill(); err != nil {
+            return nil, err
+        }
+    }
+    s.recs = nil
+    runs := s.runs
+    s.runs = nil
+    return runs, nil
+}
+
+// close removes the runs of s.
+func (s *sorter) close() {
+    removeRuns(s.runs)
+}
+
+func removeRuns(runs []string) {
+    for _, name := range runs {
+        os.Remove(name)
+    }
+}
+
+// countFile hashes the lines of the file name, the seq-th counted, unless it
+// is binary, like counter.countFile.
+func (s *sorter) countFile(name string, seq int) error {
+    f, err := os.Open(name)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+        return nil
+    }
+    defer f.Close()
+    r := bufio.NewReaderSize(f, sniffLen)
+    if isBinary(r) {
+        return nil
+    }
+    return s.countLines(r, name, seq)
+}
+
+func (s *sorter) countLines(r io.Reader, name string, seq int) error {
+    var start, offset uint64
+    input := newScanner(r)
+    input.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+        advance, token, err := bufio.ScanLines(data, atEOF)
+        start = offset
+        offset += uint64(advance)
+        return advance, token, err
+    })
+    h := newHash()
+    for n := uint64(1); input.Scan(); n++ {
+        h.Reset()
+        h.Write(input.Bytes())
+        rec := record{hash: h.Sum64(), seq: uint32(seq), length: uint32(len(input.Bytes())), line: n, offset: start}
+        if err := s.add(rec); err != nil {
+            return err
+        }
+    }
+    if err := input.Err(); err != nil {
+        return fmt.Errorf("reading %s: %v", name, err)
+    }
+    return nil
+}
+
+// A run is a sorted sequence of records on disk.
+type run struct {
+    r    *bufio.Reader
+    head record
+}
+
+// next sets the head of r to its next record, reporting false at its end.
+func (r *run) next() bool {
+    var ok bool
+    r.head, ok = readRecord(r.r)
+    return ok
+}
+
+// A runHeap orders runs by their head records.
+type runHeap []*run
+
+func (h runHeap) Len() int            { return len(h) }
+func (h runHeap) Less(i, j int) bool  { return h[i].head.less(h[j].head) }
+func (h runHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x interface{}) { *h = append(*h, x.(*run)) }
+func (h *runHeap) Pop() interface{} {
+    old := *h
+    r := old[len(old)-1]
+    *h = old[:len(old)-1]
+    return r
+}
+
+// mergeRuns merges the runs, calling visit with each record in order, and
+// removes them. While there are more than fanIn runs, they are first merged
+// fanIn at a time into longer runs, so that no more than fanIn runs are open
+// and buffered at once.
+func mergeRuns(runs []string, fanIn int, visit func(r record) error) error {
+    defer func() { removeRuns(runs) }()
+    for len(runs) > fanIn {
+        var merged []string
+        for i := 0; i < len(runs); i += fanIn {
+            j := i + fanIn
+            if j > len(runs) {
+                j = len(runs)
+            }
+            name, err := mergeToFile(runs[i:j])
+            if name != "" {
+                merged = append(merged, name)
+            }
+            if err != nil {
+                removeRuns(merged)
+                return err
+            }
+        }
+        removeRuns(runs)
+        runs = merged
+    }
+    return merge(runs, visit)
+}
+
+// mergeToFile merges runs into a new run.
+func mergeToFile(runs []string) (string, error) {
+    f, err := os.CreateTemp("", "dup-*")
+    if err != nil {
+        return "", err
+    }
+    w := bufio.NewWriterSize(f, runBuffer)
+    err = merge(runs, func(r record) error { return writeRecord(w, r) })
+    if err == nil {
+        err = w.Flush()
+    }
+    if cerr := f.Close(); err == nil {
+        err = cerr
+    }
+    return f.Name(), err
+}
+
+// merge calls visit with the records of runs in order.
+func merge(runs []string, visit func(r record) error) error {
+    var h runHeap
+    for _, name := range runs {
+        f, err := os.Open(name)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        r := &run{r: bufio.NewReaderSize(f, runBuffer)}
+        if r.next() {
+            h = append(h, r)
+        }
+    }
+    heap.Init(&h)
+    for len(h) > 0 {
+        r := h[0]
+        if err := visit(r.head); err != nil {
+            return err
+        }
+        if r.next() {
+            heap.Fix(&h, 0)
+        } else {
+            heap.Pop(&h)
+        }
+    }
+    return nil
+}
+
+// A lineReader reads lines again from the files counted, keeping a few of
+// them open.
+type lineReader struct {
+    paths []string
+    open  map[uint32]*os.File
+}
+
+// maxOpen is the number of files a lineReader keeps open.
+const maxOpen = 64
+
+// read reads the line of r into buf, growing it if needed.
+func (lr *lineReader) read(r record, buf []byte) ([]byte, error) {
+    f := lr.open[r.seq]
+    if f == nil {
+        if len(lr.open) >= maxOpen {
+            lr.close()
+        }
+        var err error
+        if f, err = os.Open(lr.paths[r.seq]); err != nil {
+            return nil, err
+        }
+        lr.open[r.seq] = f
+    }
+    if cap(buf) < int(r.length) {
+        buf = make([]byte, r.length)
+    }
+    line := buf[:r.length]
+    _, err := f.ReadAt(line, int64(r.offset))
+    return line, err
+}
+
+func (lr *lineReader) close() {
+    for seq, f := range lr.open {
+        f.Close()
+        delete(lr.open, seq)
+    }
+}
+
+// A content is a distinct line among those with the same hash, with its
+// first occurrence and the files it occurs in.
+type content struct {
+    line  []byte
+    first record
+    count int
+    seqs  []uint32 // in increasing order
+    id    uint64   // position among the duplicated lines, once duplicated
+}
+
+// A grouper groups the records merged by hash, then by content, keeping only
+// one line per content: the line of each other record with the same hash is
+// read into a buffer and looked up among them. The lines of records with a
+// hash of their own are never read.
+type grouper struct {
+    lr       *lineReader
+    buf      []byte
+    pending  *record                         // the first record of a hash, until another one comes
+    contents []*content                      // in order of first occurrence
+    index    map[string]*content             // contents by line
+    dups     uint64                          // number of duplicated lines
+    done     func(c *content) error          // called for each duplicated line once its hash ends, by id
+    occur    func(id uint64, r record) error // called for each occurrence of a duplicated line, if set
+}
+
+func (g *grouper) add(r record) error {
+    if g.pending != nil && g.pending.hash != r.hash {
+        g.pending = nil
+    } else if len(g.contents) > 0 && g.contents[0].first.hash != r.hash {
+        if err := g.flush(); err != nil {
+            return err
+        }
+    }
+    if len(g.contents) == 0 && g.pending == nil {
+        g.pending = &r
+        return nil
+    }
+    if g.pending != nil {
+        first := *g.pending
+        g.pending = nil
+        line, err := g.lr.read(first, nil)
+        if err != nil {
+            return err
+        }
+        g.contents = nil
+        g.index = make(map[string]*content)
+        g.newContent(line, first)
+    }
+    line, err := g.lr.read(r, g.buf)
+    if err != nil {
+        return err
+    }
+    g.buf = line
+    c, ok := g.index[string(line)]
+    if !ok {
+        g.newContent(append([]byte(nil), line...), r)
+        return nil
+    }
+    c.count++
+    if c.seqs[len(c.seqs)-1] != r.seq {
+        c.seqs = append(c.seqs, r.seq)
+    }
+    if c.count == 2 {
+        c.id = g.dups
+        g.dups++
+        if err := g.occurrence(c.id, c.first); err != nil {
+            return err
+        }
+    }
+    return g.occurrence(c.id, r)
+}
+
+func (g *grouper) newContent(line []byte, first record) {
+    c := &content{line: line, first: first, count: 1, seqs: []uint32{first.seq}}
+    g.contents = append(g.contents, c)
+    g.index[string(line)] = c
+}
+
+func (g *grouper) occurrence(id uint64, r record) error {
+    if g.occur == nil {
+        return nil
+    }
+    return g.occur(id, r)
+}
+
+// flush ends the hash being grouped.
+func (g *grouper) flush() error {
+    g.pending = nil
+    var dups []*content
+    for _, c := range g.contents {
+        if c.count > 1 {
+            dups = append(dups, c)
+        }
+    }
+    sort.Slice(dups, func(i, j int) bool { return dups[i].id < dups[j].id })
+    g.contents, g.index = nil, nil
+    for _, c := range dups {
+        if err := g.done(c); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// An entry describes a duplicated line on disk: its count, first occurrence
+// and the files it occurs in, and where its occurrences start among those
+// sorted by line.
+type entry struct {
+    count    uint64
+    occStart uint64 // number of occurrences of the lines with a smaller id
+    first    record
+    seqs     []uint32
+}
+
+// entrySize is the size of an entry on disk, followed by its seqs.
+const entrySize = 44
+
+// writeEntry writes e to w in the format read by readEntry.
+func writeEntry(w *bufio.Writer, e entry) error {
+    buf := make([]byte, entrySize+4*len(e.seqs))
+    binary.LittleEndian.PutUint64(buf[0:], e.count)
+    binary.LittleEndian.PutUint64(buf[8:], e.occStart)
+    binary.LittleEndian.PutUint32(buf[16:], e.first.seq)
+    binary.LittleEndian.PutUint32(buf[20:], e.first.length)
+    binary.LittleEndian.PutUint64(buf[24:], e.first.line)
+    binary.LittleEndian.PutUint64(buf[32:], e.first.offset)
+    binary.LittleEndian.PutUint32(buf[40:], uint32(len(e.seqs)))
+    for i, seq := range e.seqs {
+        binary.LittleEndian.PutUint32(buf[entrySize+4*i:], seq)
+    }
+    _, err := w.Write(buf)
+    return err
+}
+
+// readEntry reads the entry at offset in f.
+func readEntry(f *os.File, offset int64) (entry, error) {
+    var buf [entrySize]byte
+    if _, err := f.ReadAt(buf[:], offset); err != nil {
+        return entry{}, err
+    }
+    e := entry{
+        count:    binary.LittleEndian.Uint64(buf[0:]),
+        occStart: binary.LittleEndian.Uint64(buf[8:]),
+        first: record{
+            seq:    binary.LittleEndian.Uint32(buf[16:]),
+            length: binary.LittleEndian.Uint32(buf[20:]),
+            line:   binary.LittleEndian.Uint64(buf[24:]),
+            offset: binary.LittleEndian.Uint64(buf[32:]),
+        },
+    }
+    seqs := make([]byte, 4*binary.LittleEndian.Uint32(buf[40:]))
+    if _, err := f.ReadAt(seqs, offset+entrySize); err != nil {
+        return entry{}, err
+    }
+    for i := 0; i < len(seqs); i += 4 {
+        e.seqs = append(e.seqs, binary.LittleEndian.Uint32(seqs[i:]))
+    }
+    return e, nil
+}
+
+// A lowMemCount holds the duplicated lines counted by countLowMem, on disk:
+// an entry for each, records ordering them by -sort with the offsets of
+// their entries and, for the grep and json formats, their occurrences sorted
+// by id, then location.
+type lowMemCount struct {
+    files   []string // names of the files counted, by seq
+    lr      lineReader
+    fanIn   int
+    stdin   string // the copy of the standard input, if read
+    entries *os.File
+    order   []string      // runs of the ordering records
+    occs    *os.File      // nil for -format summary
+    occr    *bufio.Reader // reads the occurrences of a line
+}
+
+// countLowMem counts the lines of the files named or found below names, or
+// of the standard input if there are none, keeping in memory at most -mem
+// MiB of records and merge buffers instead of the lines. Once all files are
+// read, the records are merged by hash and the lines with equal hashes read
+// again and compared, so that collisions are not taken for duplicates. The
+// duplicated lines are then kept on disk too, see lowMemCount, and ordered
+// by another external sort.
+func countLowMem(names []string, n int) (_ *lowMemCount, err error) {
+    b := newBudget(*memBudget)
+    sorters := make([]*sorter, n)
+    for i := range sorters {
+        sorters[i] = &sorter{max: b.sortMem / n}
+        defer sorters[i].close()
+    }
+    lm := &lowMemCount{fanIn: b.fanIn}
+    defer func() {
+        if err != nil {
+            lm.close()
+        }
+    }()
+    errs := make([]error, n)
+    var paths []string
+    if len(names) == 0 {
+        // Copy the standard input, whose lines can't be read again.
+        tmp, err := os.CreateTemp("", "dup-*")
+        if err != nil {
+            return nil, err
+        }
+        lm.stdin = tmp.Name()
+        _, err = io.Copy(tmp, os.Stdin)
+        tmp.Close()
+        if err != nil {
+            return nil, err
+        }
+        lm.files, paths = []string{os.Stdin.Name()}, []string{lm.stdin}
+        f, err := os.Open(lm.stdin)
+        if err != nil {
+            return nil, err
+        }
+        errs[0] = sorters[0].countLines(f, lm.files[0], 0)
+        f.Close()
+    } else {
+        lm.files = forFiles(names, n, func(worker int, name string, seq int) {
+            if errs[worker] == nil {
+                errs[worker] = sorters[worker].countFile(name, seq)
+            }
+        })
+        paths = lm.files
+    }
+    for _, err := range errs {
+        if err != nil {
+            return nil, err
+        }
+    }
+    var runs []string
+    for _, s := range sorters {
+        r, err := s.finish()
+        runs = append(runs, r...)
+        if err != nil {
+            removeRuns(runs)
+            return nil, err
+        }
+    }
+
+    lm.lr = lineReader{paths: paths, open: make(map[uint32]*os.File)}
+    if lm.entries, err = os.CreateTemp("", "dup-*"); err != nil {
+        removeRuns(runs)
+        return nil, err
+    }
+    entries := bufio.NewWriterSize(lm.entries, runBuffer)
+    order := &sorter{max: b.sortMem / 2}
+    defer order.close()
+    g := grouper{lr: &lm.lr}
+    var occs *sorter
+    if *format != "summary" {
+        occs = &sorter{max: b.sortMem / 2}
+        defer occs.close()
+        g.occur = func(id uint64, r record) error {
+            r.hash = id
+            return occs.add(r)
+        }
+    }
+    var offset, occStart uint64
+    g.done = func(c *content) error {
+        e := entry{uint64(c.count), occStart, c.first, c.seqs}
+        r := record{seq: c.first.seq, line: c.first.line, offset: offset}
+        switch *sortBy {
+        case "count":
+            r.hash, r.key = ^e.count, c.line // most frequent first, then by line
+        case "line":
+            r.key = c.line
+        }
+        occStart += e.count
+        offset += entrySize + 4*uint64(len(e.seqs))
+        if err := writeEntry(entries, e); err != nil {
+            return err
+        }
+        return order.add(r)
+    }
+    err = mergeRuns(runs, b.fanIn, g.add)
+    if err == nil {
+        err = g.flush()
+    }
+    if err == nil {
+        err = entries.Flush()
+    }
+    if err != nil {
+        return nil, err
+    }
+    if lm.order, err = order.finish(); err != nil {
+        return nil, err
+    }
+    if occs != nil {
+        if err = lm.sortOccurrences(occs); err != nil {
+            return nil, err
+        }
+    }
+    return lm, nil
+}
+
+// sortOccurrences merges the occurrences of s into one file.
+func (lm *lowMemCount) sortOccurrences(s *sorter) error {
+    runs, err := s.finish()
+    if err != nil {
+        return err
+    }
+    if lm.occs, err = os.CreateTemp("", "dup-*"); err != nil {
+        removeRuns(runs)
+        return err
+    }
+    w := bufio.NewWriterSize(lm.occs, runBuffer)
+    err = mergeRuns(runs, lm.fanIn, func(r record) error { return writeRecord(w, r) })
+    if err == nil {
+        err = w.Flush()
+    }
+    lm.occr = bufio.NewReaderSize(nil, runBuffer)
+    return err
+}
+
+// errTop stops the merge of the ordering records once -top lines are done.
+var errTop = errors.New("top lines done")
+
+// duplicates returns the duplicated lines of lm to print, the first top of
+// them if top > 0. They can be visited only once.
+func (lm *lowMemCount) duplicates(top int) dupsFunc {
+    return func(visit func(d dup) error) error {
+        runs := lm.order
+        lm.order = nil
+        n := 0
+        err := mergeRuns(runs, lm.fanIn, func(r record) error {
+            if top > 0 && n == top {
+                return errTop
+            }
+            n++
+            e, err := readEntry(lm.entries, int64(r.offset))
+            if err != nil {
+                return err
+            }
+            line, err := lm.lr.read(e.first, nil)
+            if err != nil {
+                return err
+            }
+            return visit(dup{string(line), int(e.count), lm.fileNames(e.seqs),
+                func(visit func(loc location) error) error { return lm.occurrences(e, visit) }})
+        })
+        if err == errTop {
+            err = nil
+        }
+        return err
+    }
+}
+
+// fileNames returns the sorted names of the files seqs.
+func (lm *lowMemCount) fileNames(seqs []uint32) []string {
+    seen := make(set)
+    var names []string
+    for _, seq := range seqs {
+        if name := lm.files[seq]; !seen[name] {
+            seen[name] = true
+            names = append(names, name)
+        }
+    }
+    sort.Strings(names)
+    return names
+}
+
+// occurrences calls visit with the occurrences of the line of e in order.
+func (lm *lowMemCount) occurrences(e entry, visit func(loc location) error) error {
+    if lm.occs == nil {
+        return nil
+    }
+    lm.occr.Reset(io.NewSectionReader(lm.occs, int64(e.occStart)*recordSize, int64(e.count)*recordSize))
+    for {
+        r, ok := readRecord(lm.occr)
+        if !ok {
+            return nil
+        }
+        if err := visit(location{lm.files[r.seq], int(r.line), int(r.seq)}); err != nil {
+            return err
+        }
+    }
+}
+
+// close removes the files of lm.
+func (lm *lowMemCount) close() {
+    lm.lr.close()
+    removeRuns(lm.order)
+    for _, f := range []*os.File{lm.entries, lm.occs} {
+        if f != nil {
+            f.Close()
+            os.Remove(f.Name())
+        }
+    }
+    if lm.stdin != "" {
+        os.Remove(lm.stdin)
+    }
+}
+
+//!-
@@ -0,0 +1,263 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "bufio"
+    "bytes"
+    "flag"
+    "fmt"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+)
+
+// globsFlag collects the globs of a repeated flag.
+type globsFlag []string
+
+func (f *globsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *globsFlag) Set(s string) error {
+    if _, err := path.Match(s, ""); err != nil {
+        return fmt.Errorf("bad glob %q: %v", s, err)
+    }
+    *f = append(*f, s)
+    return nil
+}
+
+var (
+    includes   globsFlag
+    excludes   globsFlag
+    ignoreName = flag.String("ignore", ".gitignore", "Name of the ignore files read in each directory, none if empty")
+    follow     = flag.Bool("follow", false, "Follow symbolic links found in directories")
+)
+
+func init() {
+    flag.Var(&includes, "include", "Count only the files matching a glob like *.go in directories (repeatable)")
+    flag.Var(&excludes, "exclude", "Skip the files and directories matching a glob like vendor/ (repeatable)")
+}
+
+// skipDirs are the directories of version control systems, never counted.
+var skipDirs = map[string]bool{".git": true, ".hg": true, ".svn": true}
+
+// sniffLen is how much of a file is sniffed for binary content, as git does.
+const sniffLen = 8000
+
+// A rule is a pattern of an ignore file or of -include or -exclude, with the
+// syntax of .gitignore: a pattern with a slash, other than at its end, is
+// relative to dir, else it matches names at any depth; "**" matches any
+// number of directories, a trailing slash matches directories only and a
+// leading "!" negates the pattern.
+type rule struct {
+    dir     string   // directory the pattern is relative to
+    pattern []string // path elements
+    negate  bool
+    dirOnly bool
+}
+
+// parseRule parses the pattern s relative to dir, reporting false for blank
+// lines and comments.
+func parseRule(s, dir string) (rule, bool) {
+    s = strings.TrimRight(s, " \t\r")
+    if s == "" || strings.HasPrefix(s, "#") {
+        return rule{}, false
+    }
+    r := rule{dir: dir}
+    if strings.HasPrefix(s, "!") {
+        r.negate, s = true, s[1:]
+    } else if strings.HasPrefix(s, `\`) {
+        s = s[1:] // escaped leading "!" or "#"
+    }
+    if strings.HasSuffix(s, "/") {
+        r.dirOnly, s = true, strings.TrimRight(s, "/")
+    }
+    if s == "" {
+        return rule{}, false
+    }
+    if !strings.Contains(s, "/") {
+        s = "**/" + s
+    }
+    r.pattern = strings.Split(strings.TrimPrefix(s, "/"), "/")
+    return r, true
+}
+
+// matches reports whether the rule matches name, a file or, if isDir, a
+// directory below r.dir.
+func (r rule) matches(name string, isDir bool) bool {
+    if r.dirOnly && !isDir {
+        return false
+    }
+    rel, err := filepath.Rel(r.dir, name)
+    if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+        return false
+    }
+    return matchElems(r.pattern, strings.Split(filepath.ToSlash(rel), "/"))
+}
+
+// matchElems reports whether the path elements elems match those of pattern.
+func matchElems(pattern, elems []string) bool {
+    for len(pattern) > 0 {
+        if pattern[0] == "**" {
+            for i := 0; i <= len(elems); i++ {
+                if matchElems(pattern[1:], elems[i:]) {
+                    return true
+                }
+            }
+            return false
+        }
+        if len(elems) == 0 {
+            return false
+        }
+        if ok, _ := path.Match(pattern[0], elems[0]); !ok {
+            return false
+        }
+        pattern, elems = pattern[1:], elems[1:]
+    }
+    return len(elems) == 0
+}
+
+// ignored reports whether name is ignored by rules, of which the last one
+// matching wins.
+func ignored(rules []rule, name string, isDir bool) bool {
+    ignore := false
+    for _, r := range rules {
+        if r.matches(name, isDir) {
+            ignore = !r.negate
+        }
+    }
+    return ignore
+}
+
+// readIgnoreFile appends to rules those of the ignore file in dir, if any.
+func readIgnoreFile(rules []rule, dir string) []rule {
+    if *ignoreName == "" {
+        return rules
+    }
+    f, err := os.Open(filepath.Join(dir, *ignoreName))
+    if err != nil {
+        if !os.IsNotExist(err) {
+            fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+        }
+        return rules
+    }
+    defer f.Close()
+    // Copy rules so that the rules of sibling directories don't share them.
+    rules = append([]rule(nil), rules...)
+    input := bufio.NewScanner(f)
+    for input.Scan() {
+        if r, ok := parseRule(input.Text(), dir); ok {
+            rules = append(rules, r)
+        }
+    }
+    return rules
+}
+
+// walk calls visit for the file root or, if root is a directory, for the
+// files below it in lexical order, except those excluded, not included or
+// ignored. Symbolic links in directories are skipped unless -follow is set,
+// in which case links back to a directory being walked are reported as
+// loops.
+func walk(root string, visit func(name string)) {
+    info, err := os.Stat(root)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+        return
+    }
+    if !info.IsDir() {
+        visit(root) // files named on the command line are always counted
+        return
+    }
+    var rules []rule
+    for _, glob := range excludes {
+        if r, ok := parseRule(glob, root); ok {
+            rules = append(rules, r)
+        }
+    }
+    var incl []rule
+    for _, glob := range includes {
+        if r, ok := parseRule(glob, root); ok {
+            incl = append(incl, r)
+        }
+    }
+    w := walker{include: incl, visit: visit}
+    w.walkDir(root, readIgnoreFile(rules, root), []os.FileInfo{info})
+}
+
+// A walker walks directory trees for walk.
+type walker struct {
+    include []rule
+    visit   func(name string)
+}
+
+// walkDir walks dir, applying rules, below the directories ancestors.
+func (w *walker) walkDir(dir string, rules []rule, ancestors []os.FileInfo) {
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+        return
+    }
+    for _, e := range entries {
+        name := filepath.Join(dir, e.Name())
+        isDir, regular := e.IsDir(), e.Type().IsRegular()
+        var info os.FileInfo
+        if e.Type()&os.ModeSymlink != 0 {
+            if !*follow {
+                continue
+            }
+            if info, err = os.Stat(name); err != nil {
+                fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+                continue
+            }
+            isDir, regular = info.IsDir(), info.Mode().IsRegular()
+        }
+        if isDir && skipDirs[e.Name()] || ignored(rules, name, isDir) {
+            continue
+        }
+        if !isDir {
+            if regular && (len(w.include) == 0 || w.included(name)) {
+                w.visit(name)
+            }
+            continue
+        }
+        if info == nil {
+            if info, err = e.Info(); err != nil {
+                fmt.Fprintf(os.Stderr, "dup2: %v\n", err)
+                continue
+            }
+        }
+        loop := false
+        for _, a := range ancestors {
+            if os.SameFile(a, info) {
+                loop = true
+            }
+        }
+        if loop {
+            fmt.Fprintf(os.Stderr, "dup2: %s: symbolic link loop\n", name)
+            continue
+        }
+        w.walkDir(name, readIgnoreFile(rules, name), append(ancestors[:len(ancestors):len(ancestors)], info))
+    }
+}
+
+// included reports whether the file name matches an -include glob.
+func (w *walker) included(name string) bool {
+    for _, r := range w.include {
+        if r.matches(name, false) {
+            return true
+        }
+    }
+    return false
+}
+
+// isBinary reports whether the content ahead in r looks binary, that is has
+// a NUL byte in its first sniffLen bytes, without consuming it.
+func isBinary(r *bufio.Reader) bool {
+    head, _ := r.Peek(sniffLen)
+    return bytes.IndexByte(head, 0) >= 0
+}
+
+//!-
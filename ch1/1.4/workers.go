@@ -0,0 +1,89 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "sort"
+    "sync"
+)
+
+// countFiles counts the lines of the files named or found below names, see
+// forFiles, with n workers. Each worker counts into its own counter, and the
+// counters are merged once all workers are done, with the locations of each
+// line sorted as if the files had been counted one after the other, so that
+// the output doesn't depend on n. A worker stops counting at its first
+// error, which countFiles returns.
+func countFiles(names []string, n int) (*counter, error) {
+    counters := make([]*counter, n)
+    for i := range counters {
+        counters[i] = newCounter()
+    }
+    errs := make([]error, n)
+    forFiles(names, n, func(worker int, name string, seq int) {
+        if errs[worker] == nil {
+            errs[worker] = counters[worker].countFile(name, seq)
+        }
+    })
+    for _, err := range errs {
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    c := newCounter()
+    for _, w := range counters {
+        c.merge(w)
+    }
+    for _, locs := range c.locs {
+        if len(locs) > 1 {
+            sort.Slice(locs, func(i, j int) bool { return locs[i].before(locs[j]) })
+        }
+    }
+    return c, nil
+}
+
+// forFiles calls count for each file named or found below names, see walk,
+// with the position of the file among them. The files are read by n workers
+// in parallel: calls of count with the same worker number are sequential.
+// forFiles returns the files in the order found.
+func forFiles(names []string, n int, count func(worker int, name string, seq int)) []string {
+    type file struct {
+        name string
+        seq  int
+    }
+    files := make(chan file)
+    var wg sync.WaitGroup
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        go func(worker int) {
+            defer wg.Done()
+            for f := range files {
+                count(worker, f.name, f.seq)
+            }
+        }(i)
+    }
+
+    var found []string
+    for _, name := range names {
+        walk(name, func(name string) {
+            files <- file{name, len(found)}
+            found = append(found, name)
+        })
+    }
+    close(files)
+    wg.Wait()
+    return found
+}
+
+// merge adds the counts and locations of other to c.
+func (c *counter) merge(other *counter) {
+    for line, n := range other.counts {
+        c.counts[line] += n
+        c.locs[line] = append(c.locs[line], other.locs[line]...)
+    }
+}
+
+//!-
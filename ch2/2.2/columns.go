@@ -0,0 +1,165 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "io"
+    "main/table"
+    "main/unitconv"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// A column is a column of a CSV file to convert, given as
+// "weight:pound->kilogram". The column is named by its header or by its
+// position, counting from 1.
+type column struct {
+    spec           string
+    name, from, to string
+
+    index    int // position in the records, from 0
+    fromUnit *unitconv.Unit
+    toUnit   *unitconv.Unit
+    path     unitconv.Path
+}
+
+// columnsFlag collects the repeated -col flags.
+type columnsFlag []*column
+
+func (f *columnsFlag) String() string {
+    var specs []string
+    for _, c := range *f {
+        specs = append(specs, c.spec)
+    }
+    return strings.Join(specs, ",")
+}
+
+func (f *columnsFlag) Set(s string) error {
+    arrow := strings.Index(s, "->")
+    if arrow < 0 {
+        return fmt.Errorf("%q is not like weight:pound->kilogram", s)
+    }
+    colon := strings.LastIndex(s[:arrow], ":")
+    if colon < 0 {
+        return fmt.Errorf("%q is not like weight:pound->kilogram", s)
+    }
+    c := &column{spec: s, name: s[:colon],
+        from: strings.TrimSpace(s[colon+1 : arrow]), to: strings.TrimSpace(s[arrow+2:])}
+    if c.name == "" || c.from == "" || c.to == "" {
+        return fmt.Errorf("%q is not like weight:pound->kilogram", s)
+    }
+    *f = append(*f, c)
+    return nil
+}
+
+var columns columnsFlag
+
+func init() {
+    flag.Var(&columns, "col",
+        `Convert a CSV column in place, like "weight:pound->kilogram" (repeatable)`)
+}
+
+// resolve finds the units and the conversion of c.
+func (c *column) resolve() error {
+    var err error
+    if c.fromUnit, err = units.Resolve(c.from); err != nil {
+        return err
+    }
+    if c.toUnit, err = units.Resolve(c.to); err != nil {
+        return err
+    }
+    c.path, err = units.PathBetweenAt(c.fromUnit, c.toUnit, asOf)
+    return err
+}
+
+// find sets the index of c from the header, matching its name, or else its
+// position.
+func (c *column) find(header []string) error {
+    for i, name := range header {
+        if strings.TrimSpace(name) == c.name {
+            c.index = i
+            return nil
+        }
+    }
+    for i, name := range header {
+        if strings.EqualFold(strings.TrimSpace(name), c.name) {
+            c.index = i
+            return nil
+        }
+    }
+    if n, err := strconv.Atoi(c.name); err == nil && n >= 1 && n <= len(header) {
+        c.index = n - 1
+        return nil
+    }
+    return fmt.Errorf("no column %q in header %q", c.name, header)
+}
+
+// convertCSV copies the CSV records of in to out, or with -tsv records
+// separated by tabs, converting the cells of the -col columns. The first
+// record is the header, which is copied unchanged, like empty cells. With
+// -tsv, the other cells are copied as they are, while CSV cells are quoted
+// again where needed. Records are converted one at a time so that inputs
+// of any size stream through. Malformed numbers are reported with their
+// line and left as they are, in which case convertCSV returns false.
+func convertCSV(in io.Reader, out io.Writer) bool {
+    r := table.NewReader(in, *tsv)
+    w := table.NewWriter(out, *tsv)
+
+    header, err := r.Read()
+    if err == io.EOF {
+        return true
+    }
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return false
+    }
+    for _, c := range columns {
+        if err := c.find(header); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return false
+        }
+    }
+    w.Write(header)
+
+    ok := true
+    for {
+        record, err := r.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            ok = false
+            break
+        }
+        for _, c := range columns {
+            cell := strings.TrimSpace(record[c.index])
+            if cell == "" {
+                continue
+            }
+            res, err := convertValue(cell, c.fromUnit, c.toUnit, c.path)
+            if err != nil {
+                line, _ := r.FieldPos(c.index)
+                fmt.Fprintf(os.Stderr, "error: line %d, column %q: %v\n", line, c.name, err)
+                ok = false
+                continue
+            }
+            record[c.index] = string(res.Result)
+        }
+        w.Write(record)
+    }
+    w.Flush()
+    if err := w.Error(); err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return false
+    }
+    return ok
+}
+
+//!-
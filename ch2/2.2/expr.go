@@ -0,0 +1,85 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "fmt"
+    "main/metric"
+    "main/unitconv"
+    "strings"
+)
+
+// An expr is a conversion request such as "12ft -> m", "98.6 °F in celsius"
+// or "3 kg lb".
+type expr struct {
+    value    string
+    from, to *unitconv.Unit
+}
+
+// exprSeparators may stand between the source and the destination unit.
+var exprSeparators = []string{"->", "=>", "to", "in", "as"}
+
+// parseExpr parses a number followed by a source and a destination unit.
+func parseExpr(s string) (expr, error) {
+    num, rest, err := metric.SplitQuantity(s)
+    if err != nil {
+        return expr{}, fmt.Errorf("invalid expression %q: %v", strings.TrimSpace(s), err)
+    }
+
+    for _, sep := range []string{"->", "=>"} {
+        rest = strings.Replace(rest, sep, " "+sep+" ", 1)
+    }
+    words := strings.Fields(rest)
+
+    // Prefer an explicit separator, then try every split of the words into
+    // two units, so that multi-word names like "gas mark" still work.
+    for i, word := range words {
+        for _, sep := range exprSeparators {
+            if strings.ToLower(word) != sep {
+                continue
+            }
+            from, ok1 := lookupUnit(words[:i])
+            to, ok2 := lookupUnit(words[i+1:])
+            if ok1 && ok2 {
+                return expr{num, from, to}, nil
+            }
+        }
+    }
+    for i := 1; i < len(words); i++ {
+        from, ok1 := lookupUnit(words[:i])
+        to, ok2 := lookupUnit(words[i:])
+        if ok1 && ok2 {
+            return expr{num, from, to}, nil
+        }
+    }
+    return expr{}, fmt.Errorf("can't find two known units in %q, available units are %v", strings.TrimSpace(rest), units.Names())
+}
+
+func lookupUnit(words []string) (*unitconv.Unit, bool) {
+    if len(words) == 0 {
+        return nil, false
+    }
+    u, err := units.Resolve(strings.Join(words, " "))
+    return u, err == nil
+}
+
+// evalExpr parses and prints the conversion s.
+func evalExpr(s string) error {
+    e, err := parseExpr(s)
+    if err != nil {
+        return err
+    }
+    path, err := units.PathBetweenAt(e.from, e.to, asOf)
+    if err != nil {
+        return err
+    }
+    if *explain {
+        printExplanation(e.from, path)
+    }
+    return printConversion(e.value, e.from, e.to, path)
+}
+
+//!-
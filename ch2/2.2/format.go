@@ -0,0 +1,147 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "main/unitconv"
+    "math/big"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// formatFloat formats v according to -notation and -prec.
+func formatFloat(v float64) string {
+    switch *notation {
+    case "fixed":
+        return strconv.FormatFloat(v, 'f', *prec, 64)
+    case "sci":
+        return strconv.FormatFloat(v, 'e', sigDecimals(*prec), 64)
+    case "eng":
+        return engineering(strconv.FormatFloat(v, 'e', sigDecimals(*prec), 64))
+    }
+    return strconv.FormatFloat(v, 'g', *prec, 64)
+}
+
+// formatRat is like formatFloat for the exact value v. As v may have no
+// shortest form, a negative -prec stands for -digits.
+func formatRat(v *big.Rat) string {
+    p := *prec
+    if p < 0 {
+        p = *digits
+    }
+    if *notation == "fixed" {
+        return v.FloatString(p)
+    }
+    f := new(big.Float).SetPrec(256).SetRat(v)
+    switch *notation {
+    case "sci":
+        return f.Text('e', sigDecimals(p))
+    case "eng":
+        return engineering(f.Text('e', sigDecimals(p)))
+    }
+    return f.Text('g', p)
+}
+
+// sigDecimals returns the decimals after the point of a number in scientific
+// notation with the given significant digits.
+func sigDecimals(sig int) int {
+    if sig < 0 {
+        return -1
+    }
+    if sig == 0 {
+        return 0
+    }
+    return sig - 1
+}
+
+// engineering rewrites a number in scientific notation like "1.2345e+04" so
+// that its exponent is a multiple of three, like "12.345e+03".
+func engineering(s string) string {
+    i := strings.IndexByte(s, 'e')
+    if i < 0 {
+        return s // Inf or NaN
+    }
+    exp, err := strconv.Atoi(s[i+1:])
+    if err != nil {
+        return s
+    }
+    mant, sign := s[:i], ""
+    if strings.HasPrefix(mant, "-") {
+        mant, sign = mant[1:], "-"
+    }
+    shift := (exp%3 + 3) % 3
+    digits := strings.Replace(mant, ".", "", 1)
+    for len(digits) < 1+shift {
+        digits += "0"
+    }
+    num := digits[:1+shift]
+    if frac := digits[1+shift:]; frac != "" {
+        num += "." + frac
+    }
+    return fmt.Sprintf("%s%se%+03d", sign, num, exp-shift)
+}
+
+// decimalCommas lists the languages writing a comma before the decimals.
+var decimalCommas = map[string]bool{
+    "cs": true, "da": true, "de": true, "es": true, "fi": true, "fr": true,
+    "id": true, "it": true, "nb": true, "nl": true, "pl": true, "pt": true,
+    "ru": true, "sv": true, "tr": true, "uk": true,
+}
+
+// localize replaces the decimal point of num as usual in -locale.
+func localize(num string) string {
+    lang := strings.ToLower(*locale)
+    if i := strings.IndexAny(lang, "_-."); i >= 0 {
+        lang = lang[:i]
+    }
+    if decimalCommas[lang] {
+        return strings.Replace(num, ".", ",", 1)
+    }
+    return num
+}
+
+// label writes the number num with the symbol of u or, with -names, its name.
+func label(u *unitconv.Unit, num json.Number) string {
+    s := localize(string(num))
+    if *longNames {
+        return s + " " + u.Name
+    }
+    return u.FormatNumber(s)
+}
+
+// csvOut writes the results in -output csv mode, nil before the header.
+var csvOut *csv.Writer
+
+// printResult prints res in the format chosen by -output.
+func printResult(res result, from, to *unitconv.Unit) error {
+    switch *output {
+    case "json":
+        return json.NewEncoder(os.Stdout).Encode(res)
+    case "csv":
+        unit := func(u *unitconv.Unit) string {
+            if *longNames {
+                return u.Name
+            }
+            return u.Symbol
+        }
+        if csvOut == nil {
+            csvOut = csv.NewWriter(os.Stdout)
+            csvOut.Write([]string{"value", "unit", "result", "result unit"})
+        }
+        csvOut.Write([]string{string(res.Value), unit(from), string(res.Result), unit(to)})
+        // Flush every line so that results of streamed input appear at once.
+        csvOut.Flush()
+        return csvOut.Error()
+    }
+    fmt.Println(res.Text)
+    return nil
+}
+
+//!-
@@ -0,0 +1,103 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package lengthconv
+
+import (
+    "flag"
+    "fmt"
+    "main/metric"
+    "strings"
+)
+
+var (
+    meterUnit = Unit{"meter", "m", []string{"meters", "metre", "metres"}, 1}
+    footUnit  = Unit{"foot", "ft", []string{"feet"}, 0.3048}
+)
+
+// lookupUnit returns the unit with the given name, symbol or alias, ignoring
+// case, among Meter, Foot, the Metric and the Customary units.
+func lookupUnit(name string) (Unit, bool) {
+    units := append([]Unit{meterUnit, footUnit}, Metric()...)
+    for _, u := range append(units, Customary...) {
+        if strings.EqualFold(name, u.Name) || strings.EqualFold(name, u.Symbol) {
+            return u, true
+        }
+        for _, alias := range u.Aliases {
+            if strings.EqualFold(name, alias) {
+                return u, true
+            }
+        }
+    }
+    return Unit{}, false
+}
+
+// parseLength splits a length like "3km" or "12 ft" into its number and its
+// unit.
+func parseLength(s string) (float64, Unit, error) {
+    value, name, err := metric.ParseQuantity(s)
+    if err != nil {
+        return 0, Unit{}, fmt.Errorf("invalid length %q: %v, want a number and a unit like 3km", s, err)
+    }
+    if name == "" {
+        return 0, Unit{}, fmt.Errorf("invalid length %q: missing unit like m or ft", s)
+    }
+    u, ok := lookupUnit(name)
+    if !ok {
+        return 0, Unit{}, fmt.Errorf("invalid length %q: unknown unit %q", s, name)
+    }
+    return value, u, nil
+}
+
+// Set parses a length with a unit suffix, like "3km", "12 ft" or "1.5mi",
+// and converts it to Meter. It makes *Meter a flag.Value.
+func (m *Meter) Set(s string) error {
+    v, u, err := parseLength(s)
+    if err != nil {
+        return err
+    }
+    if u.Name == footUnit.Name {
+        *m = FToM(Foot(v))
+    } else {
+        *m = u.ToMeter(v)
+    }
+    return nil
+}
+
+// Set is like Meter.Set but converts to Foot.
+func (f *Foot) Set(s string) error {
+    v, u, err := parseLength(s)
+    if err != nil {
+        return err
+    }
+    if u.Name == footUnit.Name {
+        *f = Foot(v)
+    } else {
+        *f = MToF(u.ToMeter(v))
+    }
+    return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler like Set.
+func (m *Meter) UnmarshalText(text []byte) error { return m.Set(string(text)) }
+
+// UnmarshalText implements encoding.TextUnmarshaler like Set.
+func (f *Foot) UnmarshalText(text []byte) error { return f.Set(string(text)) }
+
+// MeterFlag defines a Meter flag with the specified name, default value, and
+// usage, and returns the address of the flag variable. The flag argument must
+// have a number and a unit, e.g., "3km" or "12ft".
+func MeterFlag(name string, value Meter, usage string) *Meter {
+    flag.CommandLine.Var(&value, name, usage)
+    return &value
+}
+
+// FootFlag is like MeterFlag for a Foot flag.
+func FootFlag(name string, value Foot, usage string) *Foot {
+    flag.CommandLine.Var(&value, name, usage)
+    return &value
+}
+
+//!-
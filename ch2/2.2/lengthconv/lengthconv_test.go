@@ -0,0 +1,92 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package lengthconv
+
+import (
+    "math"
+    "testing"
+    "testing/quick"
+)
+
+func TestConv(t *testing.T) {
+    if got := FToM(1); got != 0.3048 {
+        t.Errorf("FToM(1 ft) = %v, want 0.3048 m", got)
+    }
+    if got := MToF(0.3048); got != 1 {
+        t.Errorf("MToF(0.3048 m) = %v, want 1 ft", got)
+    }
+    if got := FToM(5280); got != 1609.344 {
+        t.Errorf("FToM(5280 ft) = %v, want 1609.344 m", got)
+    }
+}
+
+func TestUnits(t *testing.T) {
+    want := map[string]float64{
+        "inch": 0.0254, "yard": 0.9144, "mile": 1609.344, "nautical mile": 1852,
+        "millimeter": 1e-3, "centimeter": 1e-2, "kilometer": 1e3, "nanometer": 1e-9,
+    }
+    for _, u := range append(Metric(), Customary...) {
+        if w, ok := want[u.Name]; ok {
+            if u.Meters != w {
+                t.Errorf("1 %s = %v m, want %v m", u.Name, u.Meters, w)
+            }
+            delete(want, u.Name)
+        }
+    }
+    for name := range want {
+        t.Errorf("no unit %s", name)
+    }
+}
+
+func TestRoundTrip(t *testing.T) {
+    f := func(v float64) bool {
+        return math.Abs(float64(MToF(FToM(Foot(v))))-v) <= 1e-15*math.Abs(v)
+    }
+    if err := quick.Check(f, nil); err != nil {
+        t.Error(err)
+    }
+    for _, u := range append(Metric(), Customary...) {
+        g := func(v float64) bool {
+            v = math.Mod(v, 1e12) // no overflow
+            return math.Abs(u.FromMeter(u.ToMeter(v))-v) <= 1e-15*math.Abs(v)
+        }
+        if err := quick.Check(g, nil); err != nil {
+            t.Errorf("%s: %v", u.Name, err)
+        }
+    }
+}
+
+func TestSet(t *testing.T) {
+    tests := []struct {
+        s    string
+        m    Meter
+        feet Foot
+    }{
+        {"3km", 3000, 3000 / 0.3048},
+        {"12 ft", 12 * 0.3048, 12},
+        {"1.5mi", 2414.016, 7920},
+        {"-2e2 cm", -2, -2 / 0.3048},
+    }
+    for _, test := range tests {
+        var m Meter
+        var f Foot
+        if err := m.Set(test.s); err != nil || math.Abs(float64(m-test.m)) > 1e-9 {
+            t.Errorf("Meter.Set(%q) = %v, %v, want %v", test.s, m, err, test.m)
+        }
+        if err := f.Set(test.s); err != nil || math.Abs(float64(f-test.feet)) > 1e-9 {
+            t.Errorf("Foot.Set(%q) = %v, %v, want %v", test.s, f, err, test.feet)
+        }
+    }
+    for _, s := range []string{"NaN m", "Inf ft", "1_000m", "0x10 m", "2.5E", "1e999 m", "12", "12 furlongs"} {
+        var m Meter
+        var f Foot
+        if m.Set(s) == nil || f.Set(s) == nil {
+            t.Errorf("Set(%q) succeeded", s)
+        }
+    }
+}
+
+//!-
@@ -0,0 +1,49 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package lengthconv
+
+import (
+    "main/metric"
+    "strings"
+)
+
+// A Unit is a unit of length other than Foot and Meter.
+type Unit struct {
+    Name, Symbol string
+    Aliases      []string
+    Meters       float64 // length of one unit
+}
+
+// ToMeter converts v in u to Meter.
+func (u Unit) ToMeter(v float64) Meter { return Meter(v * u.Meters) }
+
+// FromMeter converts a Meter length to u.
+func (u Unit) FromMeter(m Meter) float64 { return float64(m) / u.Meters }
+
+// Customary lists the imperial and US customary units of length.
+var Customary = []Unit{
+    {"inch", "in", []string{"inches"}, 0.0254},
+    {"yard", "yd", []string{"yards"}, 0.9144},
+    {"mile", "mi", []string{"miles"}, 1609.344},
+    {"nautical mile", "nmi", []string{"nautical miles"}, 1852},
+}
+
+// Metric returns the multiples of Meter with SI prefixes, like millimeter.
+func Metric() []Unit {
+    var units []Unit
+    for _, p := range metric.Prefixed("meter", "m", 0) {
+        u := Unit{p.Name, p.Symbol, p.Aliases, p.Factor}
+        metre := strings.TrimSuffix(p.Name, "meter") + "metre"
+        u.Aliases = append(u.Aliases, metre, metre+"s")
+        if p.Name == "micrometer" {
+            u.Aliases = append(u.Aliases, "micron", "microns")
+        }
+        units = append(units, u)
+    }
+    return units
+}
+
+//!-
@@ -0,0 +1,179 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "bufio"
+    "fmt"
+    "io"
+    "os"
+    "os/exec"
+    "sort"
+    "strings"
+    "unicode/utf8"
+)
+
+// A lineEditor reads lines typed on a terminal, with a history browsed by
+// the up and down arrows and completion by tab.
+type lineEditor struct {
+    in      *bufio.Reader
+    out     io.Writer
+    prompt  string
+    history []string
+    // complete returns the possible completions of line, each a full line.
+    complete func(line string) []string
+}
+
+// Control keys as read in raw mode.
+const (
+    keyCtrlC     = 3
+    keyCtrlD     = 4
+    keyBackspace = 8
+    keyTab       = 9
+    keyNewline   = 10
+    keyEnter     = 13
+    keyCtrlU     = 21
+    keyEscape    = 27
+    keyDelete    = 127
+)
+
+// readLine reads a line, returning io.EOF on Ctrl-D at an empty line.
+// Ctrl-C discards the line being typed.
+func (e *lineEditor) readLine() (string, error) {
+    var line []rune
+    pos := len(e.history) // position in history, len(e.history) for line
+    var draft []rune      // the line typed before browsing the history
+    redraw := func() {
+        fmt.Fprintf(e.out, "\r\033[K%s%s", e.prompt, string(line))
+    }
+    redraw()
+    for {
+        r, _, err := e.in.ReadRune()
+        if err != nil {
+            return "", err
+        }
+        switch r {
+        case keyEnter, keyNewline:
+            fmt.Fprint(e.out, "\r\n")
+            return string(line), nil
+        case keyCtrlD:
+            if len(line) == 0 {
+                fmt.Fprint(e.out, "\r\n")
+                return "", io.EOF
+            }
+        case keyCtrlC:
+            fmt.Fprint(e.out, "^C\r\n")
+            line, pos = nil, len(e.history)
+            redraw()
+        case keyCtrlU:
+            line = nil
+            redraw()
+        case keyBackspace, keyDelete:
+            if len(line) > 0 {
+                line = line[:len(line)-1]
+                redraw()
+            }
+        case keyTab:
+            line = e.completeLine(line)
+            redraw()
+        case keyEscape:
+            // Arrow keys send ESC [ A through ESC [ D.
+            if b, _ := e.in.ReadByte(); b != '[' {
+                continue
+            }
+            b, _ := e.in.ReadByte()
+            switch {
+            case b == 'A' && pos > 0:
+                if pos == len(e.history) {
+                    draft = line
+                }
+                pos--
+                line = []rune(e.history[pos])
+            case b == 'B' && pos < len(e.history):
+                pos++
+                if pos == len(e.history) {
+                    line = draft
+                } else {
+                    line = []rune(e.history[pos])
+                }
+            }
+            redraw()
+        default:
+            if r >= ' ' {
+                line = append(line, r)
+                fmt.Fprint(e.out, string(r))
+            }
+        }
+    }
+}
+
+// completeLine extends line by the longest prefix common to its completions,
+// listing them if there are several and the line can't be extended.
+func (e *lineEditor) completeLine(line []rune) []rune {
+    if e.complete == nil {
+        return line
+    }
+    matches := e.complete(string(line))
+    if len(matches) == 0 {
+        return line
+    }
+    prefix := matches[0]
+    for _, m := range matches[1:] {
+        for !strings.HasPrefix(m, prefix) {
+            _, size := utf8.DecodeLastRuneInString(prefix)
+            prefix = prefix[:len(prefix)-size]
+        }
+    }
+    if len(matches) > 1 && len([]rune(prefix)) <= len(line) {
+        sort.Strings(matches)
+        fmt.Fprint(e.out, "\r\n")
+        for _, m := range matches {
+            fmt.Fprintf(e.out, "%s\r\n", m)
+        }
+    }
+    if len([]rune(prefix)) < len(line) {
+        return line
+    }
+    return []rune(prefix)
+}
+
+// add appends line to the history unless it repeats the last entry.
+func (e *lineEditor) add(line string) bool {
+    if line == "" || len(e.history) > 0 && e.history[len(e.history)-1] == line {
+        return false
+    }
+    e.history = append(e.history, line)
+    return true
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+    fi, err := f.Stat()
+    return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// makeRaw puts the terminal of the standard input into raw mode, in which
+// keys are read as they are typed and not echoed, and returns a function
+// restoring the previous mode.
+func makeRaw() (restore func(), err error) {
+    state, err := stty("-g")
+    if err != nil {
+        return nil, err
+    }
+    if _, err := stty("-icanon", "-echo", "-isig", "-icrnl", "min", "1"); err != nil {
+        return nil, err
+    }
+    return func() { stty(state) }, nil
+}
+
+func stty(args ...string) (string, error) {
+    cmd := exec.Command("stty", args...)
+    cmd.Stdin = os.Stdin
+    out, err := cmd.Output()
+    return strings.TrimSpace(string(out)), err
+}
+
+//!-
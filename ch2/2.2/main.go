@@ -9,147 +9,376 @@
 // are no arguments, and converts each number into units like temperature in
 // Celsius and Fahrenheit, length in feet and meters, weight in pounds and
 // kilograms, and the like.
+//
+// Run with the "serve" argument for a JSON API over HTTP, see serve. Run
+// without arguments on a terminal for an interactive mode, see runREPL. With
+// -col, convert columns of CSV input, see convertCSV.
 package main
 
 import (
+    "bufio"
+    "encoding/json"
     "flag"
     "fmt"
-    "main/lengthconv"
-    "main/tempconv"
-    "main/weightconv"
+    "io"
+    "main/metric"
+    "main/unitconv"
+    "math"
+    "math/big"
     "os"
-    "reflect"
-    "strconv"
+    "path/filepath"
     "strings"
+    "time"
+    "unicode"
 )
 
-type Edge struct {
-    From, To  reflect.Type
-    ConvertFn reflect.Value
-}
+// units is the registry of known units.
+var units = unitconv.Default
+
+// asOf is the time of the rates to convert with, zero for the latest.
+var asOf time.Time
 
-var convertGraph = make(map[reflect.Type][]Edge)
-var allUnits = make(map[string]reflect.Type)
-var allUnitNames []string
+var (
+    from      = flag.String("from", "", "The source unit to convert from")
+    to        = flag.String("to", "", "The destination unit to convert to")
+    unitsFile = flag.String("units", "", "JSON file with additional unit definitions")
+    ratesFile = flag.String("rates", "", "JSON file with units converted by timestamped rates")
+    asOfFlag  = flag.String("asof", "", "Convert with the rates of a date like 2006-01-02 instead of the latest")
+    explain   = flag.Bool("explain", false, "Print the chain of conversions used, to the standard error unless -output is text")
+    precise   = flag.Bool("precise", false, "Convert exactly with rational arithmetic")
+    digits    = flag.Int("digits", 15, "Significant digits of results in -precise mode")
+    addr      = flag.String("addr", "localhost:8000", "Address to listen on in serve mode")
+    history   = flag.String("history", historyFile(), "History file of the interactive mode, none if empty")
+    notation  = flag.String("notation", "auto", "Notation of numbers: auto, fixed, sci or eng")
+    prec      = flag.Int("prec", -1,
+        "Decimals with -notation fixed, else significant digits; -1 for as many as needed, or -digits in -precise mode")
+    longNames = flag.Bool("names", false, "Print unit names instead of symbols")
+    locale    = flag.String("locale", "", "Locale like de_DE choosing the decimal separator of text output")
+    output    = flag.String("output", "text", "Output format: text, json (one object per line) or csv")
+    all       = flag.Bool("all", false, "Convert into every unit reachable from -from instead of -to")
+    tsv       = flag.Bool("tsv", false, "Read and write tab-separated values with -col")
+    rangeMode = flag.String("range", "permissive",
+        "What to do with temperatures below absolute zero: strict (error), clamp or permissive")
+)
 
-func init() {
-    c := reflect.TypeOf(tempconv.Celsius(0))
-    k := reflect.TypeOf(tempconv.Kelvin(0))
-    f := reflect.TypeOf(tempconv.Fahrenheit(0))
-    allUnits["celsius"] = c
-    allUnits["kelvin"] = k
-    allUnits["fahrenheit"] = f
-    convertGraph[c] = []Edge{
-        {c, f, reflect.ValueOf(tempconv.CToF)},
-        {c, k, reflect.ValueOf(tempconv.CToK)},
+func main() {
+    flag.Parse()
+
+    switch *rangeMode {
+    case "strict", "clamp", "permissive":
+    default:
+        fmt.Fprintf(os.Stderr, "error: -range must be strict, clamp or permissive, not %q\n", *rangeMode)
+        os.Exit(1)
     }
-    convertGraph[k] = []Edge{
-        {k, c, reflect.ValueOf(tempconv.KToC)},
+    switch *notation {
+    case "auto", "fixed", "sci", "eng":
+    default:
+        fmt.Fprintf(os.Stderr, "error: -notation must be auto, fixed, sci or eng, not %q\n", *notation)
+        os.Exit(1)
     }
-    convertGraph[f] = []Edge{
-        {f, c, reflect.ValueOf(tempconv.FToC)},
+    switch *output {
+    case "text", "json", "csv":
+    default:
+        fmt.Fprintf(os.Stderr, "error: -output must be text, json or csv, not %q\n", *output)
+        os.Exit(1)
     }
 
-    lb := reflect.TypeOf(weightconv.Pound(0))
-    kg := reflect.TypeOf(weightconv.Kilogram(0))
-    allUnits["pound"] = lb
-    allUnits["kilogram"] = kg
-    convertGraph[lb] = []Edge{
-        {lb, kg, reflect.ValueOf(weightconv.PToK)},
-    }
-    convertGraph[kg] = []Edge{
-        {kg, lb, reflect.ValueOf(weightconv.KToP)},
+    if *unitsFile != "" {
+        if err := units.LoadFile(*unitsFile); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            os.Exit(1)
+        }
     }
-
-    m := reflect.TypeOf(lengthconv.Meter(0))
-    ft := reflect.TypeOf(lengthconv.Foot(0))
-    allUnits["meter"] = m
-    allUnits["foot"] = ft
-    convertGraph[m] = []Edge{
-        {m, ft, reflect.ValueOf(lengthconv.MToF)},
+    if *ratesFile != "" {
+        if err := units.LoadRatesFile(*ratesFile); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            os.Exit(1)
+        }
     }
-    convertGraph[ft] = []Edge{
-        {ft, m, reflect.ValueOf(lengthconv.FToM)},
+    if *asOfFlag != "" {
+        var err error
+        if asOf, err = unitconv.ParseTime(*asOfFlag); err != nil {
+            fmt.Fprintf(os.Stderr, "error: -asof: %v\n", err)
+            os.Exit(1)
+        }
     }
 
-    for key := range allUnits {
-        allUnitNames = append(allUnitNames, key)
+    if flag.NArg() == 1 && flag.Arg(0) == "serve" {
+        serve(*addr)
+        return
     }
-}
 
-func findConvertPath(from reflect.Type, to reflect.Type) (bool, []Edge) {
-    visit := make(map[reflect.Type]bool)
-    path := make([]Edge, 0, 10)
-    return dfs(from, to, visit, path)
-}
-
-func dfs(src reflect.Type, dst reflect.Type, visit map[reflect.Type]bool, path []Edge) (bool, []Edge) {
-    visit[src] = true
-    for _, edge := range convertGraph[src] {
-        if edge.To == dst {
-            path = append(path, edge)
-            return true, path
-        }
-        if _, ok := visit[edge.To]; !ok {
-            // Node that has not been visited before
-            childPath := append(path, edge)
-            if find, subpath := dfs(edge.To, dst, visit, childPath); find {
-                return true, subpath
+    if len(columns) > 0 {
+        // Convert the columns of a CSV file or the standard input.
+        for _, c := range columns {
+            if err := c.resolve(); err != nil {
+                fmt.Fprintf(os.Stderr, "error: -col %s: %v\n", c.spec, err)
+                os.Exit(1)
+            }
+        }
+        in := io.Reader(os.Stdin)
+        if flag.NArg() > 1 {
+            fmt.Fprintf(os.Stderr, "error: -col converts one file at a time\n")
+            os.Exit(1)
+        } else if flag.NArg() == 1 {
+            f, err := os.Open(flag.Arg(0))
+            if err != nil {
+                fmt.Fprintf(os.Stderr, "error: %v\n", err)
+                os.Exit(1)
             }
+            defer f.Close()
+            in = f
         }
+        out := bufio.NewWriter(os.Stdout)
+        ok := convertCSV(in, out)
+        if err := out.Flush(); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            ok = false
+        }
+        if !ok {
+            os.Exit(1)
+        }
+        return
     }
-    return false, path
-}
 
-func main() {
-    from := flag.String("from", "", "The source unit to convert from")
-    to := flag.String("to", "", "The destination unit to convert to")
+    if *all && (*from == "" || *to != "") {
+        fmt.Fprintf(os.Stderr, "error: -all needs -from and no -to\n")
+        os.Exit(1)
+    }
 
-    flag.Parse()
+    if flag.NArg() == 0 && isTerminal(os.Stdin) && !*all {
+        if err := runREPL(*from, *to, *history); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            os.Exit(1)
+        }
+        return
+    }
 
-    if *from == "" || *to == "" {
+    if *from == "" && *to == "" {
+        // Without -from and -to, arguments and input lines are expressions
+        // such as "12 ft -> m".
+        if flag.NArg() > 0 {
+            if err := evalExpr(strings.Join(flag.Args(), " ")); err != nil {
+                fmt.Fprintf(os.Stderr, "error: %v\n", err)
+                os.Exit(1)
+            }
+            return
+        }
+        ok := scanLines(os.Stdin, func(n int, line string) bool {
+            if err := evalExpr(line); err != nil {
+                fmt.Fprintf(os.Stderr, "error: line %d: %v\n", n, err)
+                return false
+            }
+            return true
+        })
+        if !ok {
+            os.Exit(1)
+        }
+        return
+    }
+    if *from == "" || *to == "" && !*all {
         fmt.Fprintf(os.Stderr, "error: MUST specify both from and to unit\n")
         os.Exit(1)
     }
-    var fromUnit, toUnit reflect.Type
-    var ok bool
-    if fromUnit, ok = allUnits[strings.ToLower(*from)]; !ok {
-        fmt.Fprintf(os.Stderr, "error: Unrecognized unit %q, available units are %v\n", *from, allUnitNames)
+    fromUnit, err := units.Resolve(*from)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
         os.Exit(1)
     }
-    if toUnit, ok = allUnits[strings.ToLower(*to)]; !ok {
-        fmt.Fprintf(os.Stderr, "error: Unrecognized unit %q, available units are %v\n", *to, allUnitNames)
-        os.Exit(1)
+
+    var convert func(val string) error
+    if *all {
+        paths := units.PathsFrom(fromUnit, asOf)
+        if len(paths) == 0 {
+            fmt.Fprintf(os.Stderr, "error: no units to convert %s to\n", fromUnit.Name)
+            os.Exit(1)
+        }
+        if *explain {
+            for _, path := range paths {
+                printExplanation(fromUnit, path)
+            }
+        }
+        convert = func(val string) error {
+            return printAll(val, fromUnit, paths)
+        }
+    } else {
+        toUnit, err := units.Resolve(*to)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            os.Exit(1)
+        }
+        path, err := units.PathBetweenAt(fromUnit, toUnit, asOf)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            os.Exit(1)
+        }
+        if *explain {
+            printExplanation(fromUnit, path)
+        }
+        convert = func(val string) error {
+            return printConversion(val, fromUnit, toUnit, path)
+        }
     }
 
-    find, paths := findConvertPath(fromUnit, toUnit)
-    if !find {
-        fmt.Fprintf(os.Stderr, "error: Can't convert %v to %v\n", *from, *to)
+    if flag.NArg() == 0 {
+        if !readValues(os.Stdin, convert) {
+            os.Exit(1)
+        }
+        return
+    }
+    ok := true
+    for _, arg := range flag.Args() {
+        if err := convert(arg); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            ok = false
+        }
+    }
+    if !ok {
         os.Exit(1)
     }
+}
 
-    for _, arg := range flag.Args() {
-        val, err := strconv.ParseFloat(arg, 64)
+// historyFile returns the default history file in the home directory.
+func historyFile() string {
+    home, err := os.UserHomeDir()
+    if err != nil {
+        return ""
+    }
+    return filepath.Join(home, ".unitconv_history")
+}
+
+// printConversion converts the number val along path and prints the result
+// in the format chosen by -output.
+func printConversion(val string, from, to *unitconv.Unit, path unitconv.Path) error {
+    res, err := convertValue(val, from, to, path)
+    if err != nil {
+        return err
+    }
+    return printResult(res, from, to)
+}
+
+// A result is a number converted from one unit to another.
+type result struct {
+    From   string      `json:"from"`
+    To     string      `json:"to"`
+    Value  json.Number `json:"value"`
+    Result json.Number `json:"result"`
+    Text   string      `json:"text"` // e.g. "100°C = 212°F"
+}
+
+// convertValue converts the number val along path, in floating point or,
+// with -precise, exactly. Temperatures below absolute zero are handled
+// according to -range. Numbers are parsed strictly, see metric.ParseNumber,
+// and results too large for a float64 are errors.
+func convertValue(val string, from, to *unitconv.Unit, path unitconv.Path) (result, error) {
+    res := result{From: from.Name, To: to.Name}
+    f, err := metric.ParseNumber(val)
+    if err != nil {
+        return res, err
+    }
+    if *precise {
+        v, _ := new(big.Rat).SetString(strings.TrimSpace(val)) // a decimal, as f parsed
+        switch *rangeMode {
+        case "strict":
+            if err := from.CheckPhysical(f); err != nil {
+                return res, err
+            }
+        case "clamp":
+            v = from.ClampPhysicalRat(v)
+        }
+        toVal, err := path.ApplyExact(v)
         if err != nil {
-            fmt.Fprintf(os.Stderr, "error: %v\n", err)
-            os.Exit(1)
+            return res, err
         }
+        res.Value = json.Number(formatRat(v))
+        res.Result = json.Number(formatRat(toVal))
+        res.Text = fmt.Sprintf("%s = %s", label(from, res.Value), label(to, res.Result))
+        return res, nil
+    }
+
+    v := f
+    switch *rangeMode {
+    case "strict":
+        if err := from.CheckPhysical(v); err != nil {
+            return res, err
+        }
+    case "clamp":
+        v = from.ClampPhysical(v)
+    }
+    toVal := path.Apply(v)
+    if math.IsNaN(toVal) || math.IsInf(toVal, 0) {
+        return res, fmt.Errorf("%s %s is out of range in %s", val, from.Name, to.Name)
+    }
+    res.Value = json.Number(formatFloat(v))
+    res.Result = json.Number(formatFloat(toVal))
+    res.Text = fmt.Sprintf("%s = %s", label(from, res.Value), label(to, res.Result))
+    return res, nil
+}
 
-        fromVal := reflect.New(fromUnit)
-        fromVal.Elem().SetFloat(val)
-        toVal := fromVal
-        first := true
-        for _, path := range paths {
-            if first {
-                // For the first conversion, fromVal is pointer type of reflect value
-                toVal = path.ConvertFn.Call([]reflect.Value{toVal.Elem()})[0]
-                first = false
-            } else {
-                toVal = path.ConvertFn.Call([]reflect.Value{toVal})[0]
+// explainPath describes the chain of conversions starting at unit from.
+// Conversions by rate show the date of their rate.
+func explainPath(from *unitconv.Unit, path unitconv.Path) string {
+    names := []string{from.Name}
+    for _, c := range path {
+        name := c.To.Name
+        if c.Rates != nil {
+            name += fmt.Sprintf(" (rate of %s)", c.Since.Format("2006-01-02"))
+        }
+        names = append(names, name)
+    }
+    return fmt.Sprintf("path: %s (%d steps, cost %g)", strings.Join(names, " -> "), len(path), path.Cost())
+}
+
+// printExplanation prints the explanation of path for -explain, to the
+// standard error unless -output is text, so that json and csv output stay
+// parseable.
+func printExplanation(from *unitconv.Unit, path unitconv.Path) {
+    w := os.Stdout
+    if *output != "text" {
+        w = os.Stderr
+    }
+    fmt.Fprintln(w, explainPath(from, path))
+}
+
+// readValues calls convert for every number read from in. Numbers are
+// separated by whitespace or commas. Malformed numbers are reported with their
+// line number and skipped, in which case readValues returns false.
+func readValues(in io.Reader, convert func(string) error) bool {
+    return scanLines(in, func(n int, line string) bool {
+        ok := true
+        fields := strings.FieldsFunc(line, func(r rune) bool {
+            return r == ',' || unicode.IsSpace(r)
+        })
+        for _, field := range fields {
+            if err := convert(field); err != nil {
+                fmt.Fprintf(os.Stderr, "error: line %d: %v\n", n, err)
+                ok = false
             }
         }
-        fmt.Printf("%s = %s\n", fromVal, toVal)
+        return ok
+    })
+}
+
+// scanLines calls fn with every line of in that is neither blank nor a
+// comment starting with '#', and with its line number. fn reports whether it
+// handled the line without errors; scanLines reports whether all lines were.
+func scanLines(in io.Reader, fn func(n int, line string) bool) bool {
+    ok := true
+    input := bufio.NewScanner(in)
+    for n := 1; input.Scan(); n++ {
+        line := strings.TrimSpace(input.Text())
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        if !fn(n, line) {
+            ok = false
+        }
+    }
+    if err := input.Err(); err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return false
     }
+    return ok
 }
 
 //!-
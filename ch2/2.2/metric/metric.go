@@ -0,0 +1,59 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+// Package metric derives metric units from the SI prefixes and parses
+// quantities with a unit suffix.
+package metric
+
+import "math"
+
+// A Prefix is an SI prefix such as kilo, which multiplies by 10^Exp.
+type Prefix struct {
+    Name, Symbol string
+    Aliases      []string // alternative spellings of Symbol
+    Exp          int
+}
+
+// Prefixes lists the common SI prefixes. Mega and above are left out as
+// their symbols differ from milli only in case, and unit lookup ignores case.
+var Prefixes = []Prefix{
+    {"nano", "n", nil, -9},
+    {"micro", "µ", []string{"u"}, -6},
+    {"milli", "m", nil, -3},
+    {"centi", "c", nil, -2},
+    {"deci", "d", nil, -1},
+    {"kilo", "k", nil, 3},
+}
+
+// A Unit is a prefixed unit, Factor times some base unit.
+type Unit struct {
+    Name, Symbol string
+    Aliases      []string
+    Factor       float64
+}
+
+// Prefixed returns the unit with the given name and symbol combined with
+// every prefix except those named in skip, such as millimeter (mm) for
+// meter (m). The unprefixed unit is 10^exp base units, and each unit has its
+// plural as an alias.
+func Prefixed(name, symbol string, exp int, skip ...string) []Unit {
+    var units []Unit
+prefixes:
+    for _, p := range Prefixes {
+        for _, s := range skip {
+            if s == p.Name {
+                continue prefixes
+            }
+        }
+        u := Unit{p.Name + name, p.Symbol + symbol, []string{p.Name + name + "s"}, math.Pow10(p.Exp + exp)}
+        for _, alias := range p.Aliases {
+            u.Aliases = append(u.Aliases, alias+symbol)
+        }
+        units = append(units, u)
+    }
+    return units
+}
+
+//!-
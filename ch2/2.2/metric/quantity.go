@@ -0,0 +1,74 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package metric
+
+import (
+    "errors"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+)
+
+// quantityRE matches a decimal number, optionally with an exponent, and the
+// rest of a quantity. An exponent without digits is matched so that it is
+// reported as a malformed number rather than taken for a unit.
+var quantityRE = regexp.MustCompile(`^\s*([+-]?(?:\d+\.?\d*|\.\d+)(?:[eE][+-]?\d*)?)(.*)$`)
+
+// ErrNoNumber is the error of a quantity that doesn't start with a number.
+var ErrNoNumber = errors.New("missing number")
+
+// ParseQuantity splits a quantity like "37.5C", "-3 km" or "1e3 ft" into its
+// number and its unit, which is empty if missing. Only decimal numbers are
+// accepted: no NaN or infinity, hexadecimal or underscores, nor numbers too
+// large for a float64.
+func ParseQuantity(s string) (float64, string, error) {
+    _, v, unit, err := parse(s)
+    if err != nil {
+        return 0, "", err
+    }
+    return v, unit, nil
+}
+
+// SplitQuantity is like ParseQuantity but returns the number as written,
+// checked, for callers that parse it otherwise, e.g. exactly.
+func SplitQuantity(s string) (string, string, error) {
+    num, _, unit, err := parse(s)
+    if err != nil {
+        return "", "", err
+    }
+    return num, unit, nil
+}
+
+// ParseNumber parses s, a number alone, like ParseQuantity.
+func ParseNumber(s string) (float64, error) {
+    num, v, rest, err := parse(s)
+    if num == "" || rest != "" {
+        return 0, fmt.Errorf("malformed number %q", strings.TrimSpace(s))
+    }
+    if err != nil {
+        return 0, err
+    }
+    return v, nil
+}
+
+// parse splits s into its number, as written and parsed, and the rest. The
+// number is empty if s doesn't start with one.
+func parse(s string) (string, float64, string, error) {
+    m := quantityRE.FindStringSubmatch(s)
+    if m == nil {
+        return "", 0, "", ErrNoNumber
+    }
+    v, err := strconv.ParseFloat(m[1], 64)
+    if errors.Is(err, strconv.ErrRange) {
+        return m[1], 0, "", fmt.Errorf("number %s is out of range", m[1])
+    } else if err != nil {
+        return m[1], 0, "", fmt.Errorf("malformed number %q", m[1])
+    }
+    return m[1], v, strings.TrimSpace(m[2]), nil
+}
+
+//!-
@@ -0,0 +1,71 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package metric
+
+import "testing"
+
+func TestParseQuantity(t *testing.T) {
+    tests := []struct {
+        s     string
+        value float64
+        unit  string
+    }{
+        {"37.5C", 37.5, "C"},
+        {" -3 km ", -3, "km"},
+        {"+.5mi", 0.5, "mi"},
+        {"2.5e3 ft", 2500, "ft"},
+        {"1E-3kg", 0.001, "kg"},
+        {"98.6 °F", 98.6, "°F"},
+        {"7.", 7, ""},
+        {"12", 12, ""},
+    }
+    for _, test := range tests {
+        value, unit, err := ParseQuantity(test.s)
+        if err != nil || value != test.value || unit != test.unit {
+            t.Errorf("ParseQuantity(%q) = %g, %q, %v, want %g, %q", test.s, value, unit, err,
+                test.value, test.unit)
+        }
+    }
+}
+
+func TestParseQuantityErrors(t *testing.T) {
+    tests := []struct{ s, err string }{
+        {"nanC", "missing number"},
+        {"InfC", "missing number"},
+        {"-inf K", "missing number"},
+        {"C", "missing number"},
+        {"", "missing number"},
+        {"2.5E", `malformed number "2.5E"`},
+        {"2.5e+ km", `malformed number "2.5e+"`},
+        {"1e999 m", "number 1e999 is out of range"},
+    }
+    for _, test := range tests {
+        if _, _, err := ParseQuantity(test.s); err == nil || err.Error() != test.err {
+            t.Errorf("ParseQuantity(%q): %v, want %s", test.s, err, test.err)
+        }
+    }
+    // Not numbers the way Go writes them in source: the unit is left over.
+    for _, s := range []string{"1_000C", "0x10 m"} {
+        if _, unit, err := ParseQuantity(s); err == nil && (unit == "C" || unit == "m") {
+            t.Errorf("ParseQuantity(%q) parsed the number whole", s)
+        }
+    }
+}
+
+func TestParseNumber(t *testing.T) {
+    for _, s := range []string{"100", " -40 ", "1.5e3", ".5"} {
+        if _, err := ParseNumber(s); err != nil {
+            t.Errorf("ParseNumber(%q): %v", s, err)
+        }
+    }
+    for _, s := range []string{"NaN", "Inf", "-inf", "0x1p4", "1_000", "2.5E", "1e999", "12 ft", ""} {
+        if v, err := ParseNumber(s); err == nil {
+            t.Errorf("ParseNumber(%q) = %g, want an error", s, v)
+        }
+    }
+}
+
+//!-
@@ -0,0 +1,66 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "main/unitconv"
+    "os"
+    "text/tabwriter"
+)
+
+// tables counts the tables printAll has printed.
+var tables int
+
+// printAll converts the number val from unit from along each of paths and
+// prints the results as a table of unit names and values or, with -output
+// json, as an object like
+//
+//     {"value": 100, "unit": "celsius", "conversions": {"kelvin": 373.15, ...}}
+func printAll(val string, from *unitconv.Unit, paths []unitconv.Path) error {
+    var results []result
+    for _, path := range paths {
+        res, err := convertValue(val, from, path[len(path)-1].To, path)
+        if err != nil {
+            return err
+        }
+        results = append(results, res)
+    }
+
+    switch *output {
+    case "json":
+        obj := struct {
+            Value       json.Number            `json:"value"`
+            Unit        string                 `json:"unit"`
+            Conversions map[string]json.Number `json:"conversions"`
+        }{results[0].Value, from.Name, make(map[string]json.Number)}
+        for _, res := range results {
+            obj.Conversions[res.To] = res.Result
+        }
+        return json.NewEncoder(os.Stdout).Encode(obj)
+    case "csv":
+        for i, res := range results {
+            if err := printResult(res, from, paths[i][len(paths[i])-1].To); err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    if tables > 0 {
+        fmt.Println()
+    }
+    tables++
+    tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+    fmt.Fprintf(tw, "%s\t%s\n", from.Name, label(from, results[0].Value))
+    for i, res := range results {
+        fmt.Fprintf(tw, "%s\t%s\n", res.To, label(paths[i][len(paths[i])-1].To, res.Result))
+    }
+    return tw.Flush()
+}
+
+//!-
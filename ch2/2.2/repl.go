@@ -0,0 +1,238 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "main/metric"
+    "main/unitconv"
+    "os"
+    "sort"
+    "strings"
+)
+
+const replHelp = `Type a number to convert it with the current units, or an expression such
+as "12 ft -> m" or "98.6 °F in celsius", which also sets the current units.
+A number with a single unit like "3 kg" converts from that unit.
+
+    set from UNIT   set the unit to convert from
+    set to UNIT     set the unit to convert to
+    show            print the current units
+    units           list the known units
+    help            print this help
+    quit            leave (or Ctrl-D)
+`
+
+// A repl converts values typed interactively, remembering the units of the
+// last conversion.
+type repl struct {
+    from, to *unitconv.Unit
+}
+
+// runREPL reads and evaluates lines from the terminal until EOF, starting
+// with the units named by from and to, if any. Lines are appended to the
+// file history, if not empty.
+func runREPL(from, to, history string) error {
+    var s repl
+    var err error
+    if from != "" {
+        if s.from, err = units.Resolve(from); err != nil {
+            return err
+        }
+    }
+    if to != "" {
+        if s.to, err = units.Resolve(to); err != nil {
+            return err
+        }
+    }
+
+    e := &lineEditor{in: bufio.NewReader(os.Stdin), out: os.Stdout, prompt: "> ", complete: completeUnit}
+    var hist *os.File
+    if history != "" {
+        e.history = readHistory(history)
+        hist, err = os.OpenFile(history, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "warning: no history: %v\n", err)
+        } else {
+            defer hist.Close()
+        }
+    }
+
+    // Without raw mode, read whole lines as the terminal delivers them.
+    restore, err := makeRaw()
+    raw := err == nil
+    if raw {
+        defer restore()
+    }
+    input := bufio.NewScanner(e.in)
+
+    fmt.Println(`Unit converter; type "help" for help.`)
+    for {
+        var line string
+        if raw {
+            if line, err = e.readLine(); err == io.EOF {
+                return nil
+            } else if err != nil {
+                return err
+            }
+        } else {
+            fmt.Print("> ")
+            if !input.Scan() {
+                return input.Err()
+            }
+            line = input.Text()
+        }
+        line = strings.TrimSpace(line)
+        if line == "" {
+            continue
+        }
+        if e.add(line) && hist != nil {
+            fmt.Fprintln(hist, line)
+        }
+        if line == "quit" || line == "exit" {
+            return nil
+        }
+        if err := s.eval(line); err != nil {
+            // In raw mode the terminal does not translate "\n".
+            fmt.Fprintf(os.Stderr, "error: %v\r\n", err)
+        }
+    }
+}
+
+// eval runs the command or conversion line.
+func (s *repl) eval(line string) error {
+    fields := strings.Fields(line)
+    switch fields[0] {
+    case "set":
+        if len(fields) < 3 || fields[1] != "from" && fields[1] != "to" {
+            return fmt.Errorf("usage: set from|to UNIT")
+        }
+        u, err := units.Resolve(strings.Join(fields[2:], " "))
+        if err != nil {
+            return err
+        }
+        if fields[1] == "from" {
+            s.from = u
+        } else {
+            s.to = u
+        }
+        return nil
+    case "show":
+        printf("from: %s\nto: %s\n", unitName(s.from), unitName(s.to))
+        return nil
+    case "units":
+        printf("%s\n", strings.Join(units.Names(), ", "))
+        return nil
+    case "help":
+        printf("%s", replHelp)
+        return nil
+    }
+
+    num, rest, err := metric.SplitQuantity(line)
+    if errors.Is(err, metric.ErrNoNumber) {
+        return fmt.Errorf("unknown command %q, type \"help\" for help", fields[0])
+    } else if err != nil {
+        return err
+    }
+    if rest != "" {
+        if e, err := parseExpr(line); err == nil {
+            s.from, s.to = e.from, e.to
+        } else if u, err2 := units.Resolve(rest); err2 == nil {
+            s.from = u
+        } else {
+            return err
+        }
+    }
+    if s.from == nil || s.to == nil {
+        return fmt.Errorf("no units to convert between, use \"set from\" and \"set to\"")
+    }
+    path, err := units.PathBetweenAt(s.from, s.to, asOf)
+    if err != nil {
+        return err
+    }
+    if *explain {
+        printf("%s\n", explainPath(s.from, path))
+    }
+    res, err := convertValue(num, s.from, s.to, path)
+    if err != nil {
+        return err
+    }
+    printf("%s\n", res.Text)
+    return nil
+}
+
+// printf is like fmt.Printf but ends lines with "\r\n" for terminals in raw
+// mode.
+func printf(format string, args ...interface{}) {
+    fmt.Print(strings.Replace(fmt.Sprintf(format, args...), "\n", "\r\n", -1))
+}
+
+func unitName(u *unitconv.Unit) string {
+    if u == nil {
+        return "(not set)"
+    }
+    return u.Name
+}
+
+// completeUnit completes the unit name at the end of line, or a command at
+// its start. Names may contain spaces, so the longest tail of line starting a
+// known name is completed.
+func completeUnit(line string) []string {
+    var matches []string
+    for _, cmd := range []string{"help", "quit", "set from ", "set to ", "show", "units"} {
+        if strings.HasPrefix(cmd, line) {
+            matches = append(matches, cmd)
+        }
+    }
+    if len(matches) > 0 {
+        return matches
+    }
+    var names []string
+    for _, u := range units.Units() {
+        names = append(names, u.Name)
+        names = append(names, u.Aliases...)
+    }
+    sort.Strings(names)
+    for i := 0; i < len(line); i++ {
+        if i > 0 && line[i-1] != ' ' {
+            continue
+        }
+        tail := strings.ToLower(line[i:])
+        for _, name := range names {
+            if strings.HasPrefix(strings.ToLower(name), tail) {
+                matches = append(matches, line[:i]+name)
+            }
+        }
+        if len(matches) > 0 {
+            return matches
+        }
+    }
+    return nil
+}
+
+// readHistory returns the last lines of the history file.
+func readHistory(filename string) []string {
+    const maxLines = 1000
+    f, err := os.Open(filename)
+    if err != nil {
+        return nil
+    }
+    defer f.Close()
+    var lines []string
+    input := bufio.NewScanner(f)
+    for input.Scan() {
+        lines = append(lines, input.Text())
+    }
+    if len(lines) > maxLines {
+        lines = lines[len(lines)-maxLines:]
+    }
+    return lines
+}
+
+//!-
@@ -0,0 +1,182 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "main/unitconv"
+    "net/http"
+)
+
+// A request asks for the conversion of Value from unit From to unit To,
+// with the rates as of the date AsOf if set.
+type request struct {
+    From  string      `json:"from"`
+    To    string      `json:"to"`
+    Value json.Number `json:"value"`
+    AsOf  string      `json:"asof,omitempty"`
+}
+
+// A requestError is a failed request together with its HTTP status.
+type requestError struct {
+    status int
+    err    error
+}
+
+func (e *requestError) Error() string { return e.err.Error() }
+
+// serve answers conversion requests in JSON on addr:
+//
+//     GET  /convert?from=celsius&to=fahrenheit&value=100[&asof=2006-01-02]
+//     POST /convert    with a JSON array of {"from", "to", "value"} objects
+//     GET  /units      lists the known units
+//
+// Failed requests get a 4xx status and a body like {"error": "..."}. In a
+// batch, each failed conversion has an "error" in place of its result.
+func serve(addr string) {
+    http.HandleFunc("/convert", handleConvert)
+    http.HandleFunc("/units", handleUnits)
+    log.Printf("listening on %s", addr)
+    log.Fatal(http.ListenAndServe(addr, nil))
+}
+
+func handleConvert(w http.ResponseWriter, r *http.Request) {
+    switch r.Method {
+    case "GET":
+        q := r.URL.Query()
+        for _, key := range []string{"from", "to", "value"} {
+            if len(q[key]) != 1 {
+                writeError(w, http.StatusBadRequest, fmt.Errorf("query string MUST have one %q parameter", key))
+                return
+            }
+        }
+        if len(q["asof"]) > 1 {
+            writeError(w, http.StatusBadRequest, fmt.Errorf("query string MUST have at most one \"asof\" parameter"))
+            return
+        }
+        res, err := convertRequest(request{q.Get("from"), q.Get("to"), json.Number(q.Get("value")), q.Get("asof")})
+        if err != nil {
+            writeError(w, err.status, err)
+            return
+        }
+        writeJSON(w, http.StatusOK, res)
+    case "POST":
+        var reqs []request
+        dec := json.NewDecoder(r.Body)
+        dec.DisallowUnknownFields()
+        if err := dec.Decode(&reqs); err != nil {
+            writeError(w, http.StatusBadRequest, fmt.Errorf("body MUST be a JSON array of conversions: %v", err))
+            return
+        }
+        type batchResult struct {
+            *result
+            Error string `json:"error,omitempty"`
+        }
+        results := make([]batchResult, len(reqs))
+        for i, req := range reqs {
+            res, err := convertRequest(req)
+            if err != nil {
+                results[i].Error = err.Error()
+                continue
+            }
+            results[i].result = &res
+        }
+        writeJSON(w, http.StatusOK, results)
+    default:
+        w.Header().Set("Allow", "GET, POST")
+        writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+    }
+}
+
+// convertRequest converts the value of req like the command line does.
+func convertRequest(req request) (result, *requestError) {
+    from, reqErr := resolveRequestUnit(req.From)
+    if reqErr != nil {
+        return result{}, reqErr
+    }
+    to, reqErr := resolveRequestUnit(req.To)
+    if reqErr != nil {
+        return result{}, reqErr
+    }
+    t := asOf
+    var err error
+    if req.AsOf != "" {
+        if t, err = unitconv.ParseTime(req.AsOf); err != nil {
+            return result{}, &requestError{http.StatusBadRequest, err}
+        }
+    }
+    path, err := units.PathBetweenAt(from, to, t)
+    if err != nil {
+        return result{}, &requestError{http.StatusUnprocessableEntity, err}
+    }
+    res, err := convertValue(string(req.Value), from, to, path)
+    if err != nil {
+        return result{}, &requestError{http.StatusBadRequest, err}
+    }
+    return res, nil
+}
+
+// resolveRequestUnit resolves the unit name of a request without adding
+// compound units to the registry, so that requests don't make it grow. An
+// unknown unit is not found, while a malformed compound unit is a bad
+// request.
+func resolveRequestUnit(name string) (*unitconv.Unit, *requestError) {
+    u, err := units.ResolveTemporary(name)
+    if err != nil {
+        var unknown *unitconv.UnknownUnitError
+        if errors.As(err, &unknown) {
+            return nil, &requestError{http.StatusNotFound, err}
+        }
+        return nil, &requestError{http.StatusBadRequest, err}
+    }
+    return u, nil
+}
+
+func handleUnits(w http.ResponseWriter, r *http.Request) {
+    if r.Method != "GET" {
+        w.Header().Set("Allow", "GET")
+        writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+        return
+    }
+    type unit struct {
+        Name      string   `json:"name"`
+        Symbol    string   `json:"symbol"`
+        Aliases   []string `json:"aliases,omitempty"`
+        Dimension string   `json:"dimension"`
+    }
+    list := []unit{}
+    for _, u := range units.Units() {
+        list = append(list, unit{u.Name, u.Symbol, u.Aliases, u.Dimension.String()})
+    }
+    writeJSON(w, http.StatusOK, list)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+    writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes v in JSON with the given status, or else an error with
+// status 500 if v can't be encoded.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    var buf bytes.Buffer
+    if err := json.NewEncoder(&buf).Encode(v); err != nil {
+        log.Printf("encoding response: %v", err)
+        status = http.StatusInternalServerError
+        buf.Reset()
+        buf.WriteString(`{"error":"can't encode the response"}` + "\n")
+    }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    if _, err := w.Write(buf.Bytes()); err != nil {
+        log.Printf("writing response: %v", err)
+    }
+}
+
+//!-
@@ -0,0 +1,108 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+// Package table reads and writes records of comma-separated values, with
+// encoding/csv, or of tab-separated values, which are not quoted.
+package table
+
+import (
+    "bufio"
+    "encoding/csv"
+    "fmt"
+    "io"
+    "strings"
+)
+
+// A Reader reads records like csv.Reader.
+type Reader interface {
+    Read() ([]string, error)
+    FieldPos(field int) (line, column int)
+}
+
+// A Writer writes records like csv.Writer.
+type Writer interface {
+    Write(record []string) error
+    Flush()
+    Error() error
+}
+
+// NewReader returns a Reader of the CSV records of in or, if tsv is set,
+// its TSV records. The record returned by Read may be reused by the next.
+func NewReader(in io.Reader, tsv bool) Reader {
+    if tsv {
+        return &tsvReader{in: bufio.NewReader(in)}
+    }
+    r := csv.NewReader(in)
+    r.ReuseRecord = true
+    return r
+}
+
+// NewWriter returns a Writer of CSV records to out or, if tsv is set, of
+// TSV records.
+func NewWriter(out io.Writer, tsv bool) Writer {
+    if tsv {
+        return &tsvWriter{w: bufio.NewWriter(out)}
+    }
+    return csv.NewWriter(out)
+}
+
+// A tsvReader reads lines of tab-separated values. Unlike CSV, quotes have
+// no meaning, so that cells are read as they are. Empty lines are skipped
+// and every record must have as many fields as the first, as in CSV.
+type tsvReader struct {
+    in     *bufio.Reader
+    line   int
+    fields int
+}
+
+func (r *tsvReader) Read() ([]string, error) {
+    for {
+        s, err := r.in.ReadString('\n')
+        if s == "" || err != nil && err != io.EOF {
+            if err == nil {
+                err = io.EOF
+            }
+            return nil, err
+        }
+        r.line++
+        s = strings.TrimSuffix(strings.TrimSuffix(s, "\n"), "\r")
+        if s == "" {
+            continue
+        }
+        record := strings.Split(s, "\t")
+        if r.fields == 0 {
+            r.fields = len(record)
+        } else if len(record) != r.fields {
+            return nil, fmt.Errorf("record on line %d: wrong number of fields", r.line)
+        }
+        return record, nil
+    }
+}
+
+// FieldPos returns the line of the record last read; columns are not kept.
+func (r *tsvReader) FieldPos(field int) (line, column int) { return r.line, 0 }
+
+// A tsvWriter writes records as lines of tab-separated values.
+type tsvWriter struct {
+    w   *bufio.Writer
+    err error
+}
+
+func (w *tsvWriter) Write(record []string) error {
+    if w.err == nil {
+        _, w.err = w.w.WriteString(strings.Join(record, "\t") + "\n")
+    }
+    return w.err
+}
+
+func (w *tsvWriter) Flush() {
+    if err := w.w.Flush(); w.err == nil {
+        w.err = err
+    }
+}
+
+func (w *tsvWriter) Error() error { return w.err }
+
+//!-
@@ -0,0 +1,81 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package table
+
+import (
+    "bytes"
+    "io"
+    "strings"
+    "testing"
+)
+
+// copyRecords reads the records of in and writes them again, changing the
+// cells of column col, if any, with change.
+func copyRecords(in string, tsv bool, col int, change func(string) string) (string, error) {
+    r := NewReader(strings.NewReader(in), tsv)
+    var out bytes.Buffer
+    w := NewWriter(&out, tsv)
+    for {
+        record, err := r.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return out.String(), err
+        }
+        if col >= 0 {
+            record[col] = change(record[col])
+        }
+        w.Write(record)
+    }
+    w.Flush()
+    return out.String(), w.Error()
+}
+
+func TestRoundTrip(t *testing.T) {
+    tests := []struct {
+        tsv         bool
+        in, changed string
+    }{
+        {false, "name,w\n\"a, b\",3\n\"5\"\" pipe\",\nplain,-1.5\n",
+            "name,<w>\n\"a, b\",<3>\n\"5\"\" pipe\",<>\nplain,<-1.5>\n"},
+        {true, "name\tw\n  5\" pipe\t3\n\"q\"\t\na,\"b\"\t-1.5\n",
+            "name\t<w>\n  5\" pipe\t<3>\n\"q\"\t<>\na,\"b\"\t<-1.5>\n"},
+        {true, "\"\t'\n\"unbalanced\t \n", "\"\t<'>\n\"unbalanced\t< >\n"},
+    }
+    mark := func(s string) string { return "<" + s + ">" }
+    for _, test := range tests {
+        if got, err := copyRecords(test.in, test.tsv, -1, nil); err != nil || got != test.in {
+            t.Errorf("copy of %q (tsv %t) = %q, %v", test.in, test.tsv, got, err)
+        }
+        // Changing a column leaves the others as they are.
+        if got, err := copyRecords(test.in, test.tsv, 1, mark); err != nil || got != test.changed {
+            t.Errorf("copy of %q (tsv %t) changing a column = %q, %v, want %q", test.in, test.tsv,
+                got, err, test.changed)
+        }
+    }
+}
+
+func TestTSV(t *testing.T) {
+    got, err := copyRecords("a\tb\r\n\n1\t\"2\"\r\n", true, 1, func(s string) string { return s + "!" })
+    if want := "a\tb!\n1\t\"2\"!\n"; err != nil || got != want {
+        t.Errorf("copy = %q, %v, want %q", got, err, want)
+    }
+    r := NewReader(strings.NewReader("a\tb\n\n1\t2\n3\n"), true)
+    for i := 0; i < 2; i++ {
+        if _, err := r.Read(); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if line, _ := r.FieldPos(0); line != 3 {
+        t.Errorf("record on line %d, want 3", line)
+    }
+    if _, err := r.Read(); err == nil {
+        t.Errorf("read a record with a missing field")
+    }
+}
+
+//!-
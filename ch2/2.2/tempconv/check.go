@@ -0,0 +1,130 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package tempconv
+
+import "fmt"
+
+// An AbsoluteZeroError reports a temperature below absolute zero.
+type AbsoluteZeroError struct {
+    Temp fmt.Stringer
+}
+
+func (e *AbsoluteZeroError) Error() string {
+    return fmt.Sprintf("%v is below absolute zero", e.Temp)
+}
+
+// tolerance absorbs rounding errors, so that -459.67°F counts as valid.
+const tolerance = 1e-9
+
+// Valid reports whether v is a temperature at or above absolute zero on s.
+func (s Scale) Valid(v float64) bool { return s.ToKelvin(v) >= -tolerance }
+
+// Clamp returns v, or absolute zero on s if v is below it.
+func (s Scale) Clamp(v float64) float64 {
+    if s.Valid(v) {
+        return v
+    }
+    return s.FromKelvin(0)
+}
+
+func check(s Scale, v float64, t fmt.Stringer) error {
+    if !s.Valid(v) {
+        return &AbsoluteZeroError{t}
+    }
+    return nil
+}
+
+// NewCelsius returns v as a Celsius temperature, or an error if it is below
+// absolute zero. The other New functions are alike.
+func NewCelsius(v float64) (Celsius, error) {
+    if err := check(CelsiusScale, v, Celsius(v)); err != nil {
+        return 0, err
+    }
+    return Celsius(v), nil
+}
+
+func NewFahrenheit(v float64) (Fahrenheit, error) {
+    if err := check(FahrenheitScale, v, Fahrenheit(v)); err != nil {
+        return 0, err
+    }
+    return Fahrenheit(v), nil
+}
+
+func NewKelvin(v float64) (Kelvin, error) {
+    if err := check(KelvinScale, v, Kelvin(v)); err != nil {
+        return 0, err
+    }
+    return Kelvin(v), nil
+}
+
+func NewRankine(v float64) (Rankine, error) {
+    if err := check(RankineScale, v, Rankine(v)); err != nil {
+        return 0, err
+    }
+    return Rankine(v), nil
+}
+
+func NewReaumur(v float64) (Reaumur, error) {
+    if err := check(ReaumurScale, v, Reaumur(v)); err != nil {
+        return 0, err
+    }
+    return Reaumur(v), nil
+}
+
+func NewDelisle(v float64) (Delisle, error) {
+    if err := check(DelisleScale, v, Delisle(v)); err != nil {
+        return 0, err
+    }
+    return Delisle(v), nil
+}
+
+func NewNewton(v float64) (Newton, error) {
+    if err := check(NewtonScale, v, Newton(v)); err != nil {
+        return 0, err
+    }
+    return Newton(v), nil
+}
+
+func NewRomer(v float64) (Romer, error) {
+    if err := check(RomerScale, v, Romer(v)); err != nil {
+        return 0, err
+    }
+    return Romer(v), nil
+}
+
+// CToFChecked is like CToF but fails for temperatures below absolute zero.
+func CToFChecked(c Celsius) (Fahrenheit, error) {
+    if err := check(CelsiusScale, float64(c), c); err != nil {
+        return 0, err
+    }
+    return CToF(c), nil
+}
+
+// FToCChecked is like FToC but fails for temperatures below absolute zero.
+func FToCChecked(f Fahrenheit) (Celsius, error) {
+    if err := check(FahrenheitScale, float64(f), f); err != nil {
+        return 0, err
+    }
+    return FToC(f), nil
+}
+
+// CToKChecked is like CToK but fails for temperatures below absolute zero.
+func CToKChecked(c Celsius) (Kelvin, error) {
+    if err := check(CelsiusScale, float64(c), c); err != nil {
+        return 0, err
+    }
+    return CToK(c), nil
+}
+
+// KToCChecked is like KToC but fails for temperatures below absolute zero.
+func KToCChecked(k Kelvin) (Celsius, error) {
+    if err := check(KelvinScale, float64(k), k); err != nil {
+        return 0, err
+    }
+    return KToC(k), nil
+}
+
+//!-
@@ -0,0 +1,123 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package tempconv
+
+import (
+    "flag"
+    "fmt"
+    "main/metric"
+    "strings"
+)
+
+// scaleSuffixes maps the lower-cased unit suffixes of temperatures to their
+// scales.
+var scaleSuffixes = map[string]Scale{
+    "c": CelsiusScale, "°c": CelsiusScale, "celsius": CelsiusScale,
+    "f": FahrenheitScale, "°f": FahrenheitScale, "fahrenheit": FahrenheitScale,
+    "k": KelvinScale, "kelvin": KelvinScale,
+    "°r": RankineScale, "rankine": RankineScale,
+    "°ré": ReaumurScale, "réaumur": ReaumurScale, "reaumur": ReaumurScale,
+    "°de": DelisleScale, "delisle": DelisleScale,
+    "°n": NewtonScale, "newton": NewtonScale,
+    "°rø": RomerScale, "rømer": RomerScale, "romer": RomerScale,
+}
+
+// parseTemp splits a temperature like "37.5C" or "98.6 °F" into its number
+// and its scale.
+func parseTemp(s string) (float64, Scale, error) {
+    value, unit, err := metric.ParseQuantity(s)
+    if err != nil {
+        return 0, Scale{}, fmt.Errorf("invalid temperature %q: %v, want a number and a unit like 37.5C", s, err)
+    }
+    if unit == "" {
+        return 0, Scale{}, fmt.Errorf("invalid temperature %q: missing unit like C, F or K", s)
+    }
+    scale, ok := scaleSuffixes[strings.ToLower(unit)]
+    if !ok {
+        return 0, Scale{}, fmt.Errorf("invalid temperature %q: unknown unit %q, want C, F, K, °R, °Ré, °De, °N or °Rø", s, unit)
+    }
+    return value, scale, nil
+}
+
+// convertTo converts the temperature v on scale from to scale to, with the
+// conversion functions of the package where there is one.
+func convertTo(v float64, from, to Scale) float64 {
+    switch {
+    case from == to:
+        return v
+    case from == CelsiusScale && to == FahrenheitScale:
+        return float64(CToF(Celsius(v)))
+    case from == FahrenheitScale && to == CelsiusScale:
+        return float64(FToC(Fahrenheit(v)))
+    case from == CelsiusScale && to == KelvinScale:
+        return float64(CToK(Celsius(v)))
+    case from == KelvinScale && to == CelsiusScale:
+        return float64(KToC(Kelvin(v)))
+    }
+    return Convert(v, from, to)
+}
+
+// Set parses a temperature with a unit suffix, like "37.5C", "98.6°F" or
+// "310 kelvin", and converts it to Celsius. It makes *Celsius a flag.Value.
+func (c *Celsius) Set(s string) error {
+    v, scale, err := parseTemp(s)
+    if err != nil {
+        return err
+    }
+    *c = Celsius(convertTo(v, scale, CelsiusScale))
+    return nil
+}
+
+// Set is like Celsius.Set but converts to Fahrenheit.
+func (f *Fahrenheit) Set(s string) error {
+    v, scale, err := parseTemp(s)
+    if err != nil {
+        return err
+    }
+    *f = Fahrenheit(convertTo(v, scale, FahrenheitScale))
+    return nil
+}
+
+// Set is like Celsius.Set but converts to Kelvin.
+func (k *Kelvin) Set(s string) error {
+    v, scale, err := parseTemp(s)
+    if err != nil {
+        return err
+    }
+    *k = Kelvin(convertTo(v, scale, KelvinScale))
+    return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler like Set.
+func (c *Celsius) UnmarshalText(text []byte) error { return c.Set(string(text)) }
+
+// UnmarshalText implements encoding.TextUnmarshaler like Set.
+func (f *Fahrenheit) UnmarshalText(text []byte) error { return f.Set(string(text)) }
+
+// UnmarshalText implements encoding.TextUnmarshaler like Set.
+func (k *Kelvin) UnmarshalText(text []byte) error { return k.Set(string(text)) }
+
+// CelsiusFlag defines a Celsius flag with the specified name, default value,
+// and usage, and returns the address of the flag variable. The flag argument
+// must have a number and a unit, e.g., "100C" or "212°F".
+func CelsiusFlag(name string, value Celsius, usage string) *Celsius {
+    flag.CommandLine.Var(&value, name, usage)
+    return &value
+}
+
+// FahrenheitFlag is like CelsiusFlag for a Fahrenheit flag.
+func FahrenheitFlag(name string, value Fahrenheit, usage string) *Fahrenheit {
+    flag.CommandLine.Var(&value, name, usage)
+    return &value
+}
+
+// KelvinFlag is like CelsiusFlag for a Kelvin flag.
+func KelvinFlag(name string, value Kelvin, usage string) *Kelvin {
+    flag.CommandLine.Var(&value, name, usage)
+    return &value
+}
+
+//!-
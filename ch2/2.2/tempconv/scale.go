@@ -0,0 +1,62 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package tempconv
+
+import "fmt"
+
+// A Scale is an affine temperature scale: a temperature v on the scale is
+// v*Factor + Offset kelvins.
+type Scale struct {
+    Name, Symbol   string
+    Factor, Offset float64
+}
+
+var (
+    KelvinScale     = Scale{"kelvin", "K", 1, 0}
+    CelsiusScale    = Scale{"celsius", "°C", 1, 273.15}
+    FahrenheitScale = Scale{"fahrenheit", "°F", 5.0 / 9, 273.15 - 32*5.0/9}
+    RankineScale    = Scale{"rankine", "°R", 5.0 / 9, 0}
+    ReaumurScale    = Scale{"réaumur", "°Ré", 5.0 / 4, 273.15}
+    DelisleScale    = Scale{"delisle", "°De", -2.0 / 3, 373.15}
+    NewtonScale     = Scale{"newton", "°N", 100.0 / 33, 273.15}
+    RomerScale      = Scale{"rømer", "°Rø", 40.0 / 21, 273.15 - 7.5*40.0/21}
+)
+
+// ToKelvin converts the temperature v on s to Kelvin.
+func (s Scale) ToKelvin(v float64) Kelvin { return Kelvin(v*s.Factor + s.Offset) }
+
+// FromKelvin converts a Kelvin temperature to s.
+func (s Scale) FromKelvin(k Kelvin) float64 {
+    v := (float64(k) - s.Offset) / s.Factor
+    if v == 0 {
+        return 0 // not -0 on scales with a negative factor
+    }
+    return v
+}
+
+// Convert converts the temperature v on scale from to scale to.
+func Convert(v float64, from, to Scale) float64 { return to.FromKelvin(from.ToKelvin(v)) }
+
+// Diff returns the difference a-b of two temperatures on s.
+func (s Scale) Diff(a, b float64) Delta { return Delta{a - b, s} }
+
+// Raise returns the temperature v on s raised by the difference d.
+func (s Scale) Raise(v float64, d Delta) float64 { return v + d.In(s).Value }
+
+// A Delta is a difference between temperatures on some scale. Unlike
+// temperatures, differences convert by the ratio of the scale factors alone:
+// a difference of 10°C is one of 18°F, while 10°C is 50°F.
+type Delta struct {
+    Value float64
+    Scale Scale
+}
+
+// In converts d to scale s.
+func (d Delta) In(s Scale) Delta { return Delta{d.Value * d.Scale.Factor / s.Factor, s} }
+
+func (d Delta) String() string { return fmt.Sprintf("%gΔ%s", d.Value, d.Scale.Symbol) }
+
+//!-
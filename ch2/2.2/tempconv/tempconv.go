@@ -3,7 +3,7 @@
 
 //!+
 
-// Package tempconv performs Celsius and Fahrenheit conversions.
+// Package tempconv performs conversions between temperature scales.
 package tempconv
 
 import "fmt"
@@ -11,15 +11,27 @@ import "fmt"
 type Celsius float64
 type Fahrenheit float64
 type Kelvin float64
+type Rankine float64
+type Reaumur float64
+type Delisle float64
+type Newton float64
+type Romer float64
 
 const (
-    AbsoluteZeroC Celsius = -273.15
-    FreezingC     Celsius = 0
-    BoilingC      Celsius = 100
+    AbsoluteZeroC Celsius    = -273.15
+    AbsoluteZeroF Fahrenheit = -459.67
+    AbsoluteZeroK Kelvin     = 0
+    FreezingC     Celsius    = 0
+    BoilingC      Celsius    = 100
 )
 
 func (c Celsius) String() string    { return fmt.Sprintf("%g°C", c) }
 func (f Fahrenheit) String() string { return fmt.Sprintf("%g°F", f) }
 func (k Kelvin) String() string     { return fmt.Sprintf("%gK", k) }
+func (r Rankine) String() string    { return fmt.Sprintf("%g°R", r) }
+func (r Reaumur) String() string    { return fmt.Sprintf("%g°Ré", r) }
+func (d Delisle) String() string    { return fmt.Sprintf("%g°De", d) }
+func (n Newton) String() string     { return fmt.Sprintf("%g°N", n) }
+func (r Romer) String() string      { return fmt.Sprintf("%g°Rø", r) }
 
 //!-
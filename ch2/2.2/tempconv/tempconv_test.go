@@ -0,0 +1,133 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package tempconv
+
+import (
+    "math"
+    "testing"
+    "testing/quick"
+)
+
+var scales = []Scale{
+    KelvinScale, CelsiusScale, FahrenheitScale, RankineScale,
+    ReaumurScale, DelisleScale, NewtonScale, RomerScale,
+}
+
+// near reports whether got is within a few rounding errors of want.
+func near(got, want float64) bool {
+    return math.Abs(got-want) <= 1e-12*math.Max(1, math.Abs(want))
+}
+
+func TestConv(t *testing.T) {
+    if got := CToF(BoilingC); got != 212 {
+        t.Errorf("CToF(%v) = %v, want 212°F", BoilingC, got)
+    }
+    if got := CToF(FreezingC); got != 32 {
+        t.Errorf("CToF(%v) = %v, want 32°F", FreezingC, got)
+    }
+    if got := FToC(-40); got != -40 {
+        t.Errorf("FToC(-40°F) = %v, want -40°C", got)
+    }
+    if got := CToK(AbsoluteZeroC); got != AbsoluteZeroK {
+        t.Errorf("CToK(%v) = %v, want %v", AbsoluteZeroC, got, AbsoluteZeroK)
+    }
+    if got := KToC(373.15); !near(float64(got), 100) {
+        t.Errorf("KToC(373.15K) = %v, want 100°C", got)
+    }
+    if got := CToF(AbsoluteZeroC); !near(float64(got), float64(AbsoluteZeroF)) {
+        t.Errorf("CToF(%v) = %v, want %v", AbsoluteZeroC, got, AbsoluteZeroF)
+    }
+}
+
+// The boiling point of water on each scale.
+var boiling = []struct {
+    scale Scale
+    want  float64
+}{
+    {KelvinScale, 373.15},
+    {CelsiusScale, 100},
+    {FahrenheitScale, 212},
+    {RankineScale, 671.67},
+    {ReaumurScale, 80},
+    {DelisleScale, 0},
+    {NewtonScale, 33},
+    {RomerScale, 60},
+}
+
+func TestBoiling(t *testing.T) {
+    for _, test := range boiling {
+        if got := Convert(100, CelsiusScale, test.scale); !near(got, test.want) {
+            t.Errorf("100°C = %g%s, want %g%s", got, test.scale.Symbol, test.want, test.scale.Symbol)
+        }
+        if got := Convert(test.want, test.scale, CelsiusScale); !near(got, 100) {
+            t.Errorf("%g%s = %g°C, want 100°C", test.want, test.scale.Symbol, got)
+        }
+    }
+}
+
+func TestRoundTrip(t *testing.T) {
+    for _, a := range scales {
+        for _, b := range scales {
+            f := func(v float64) bool {
+                v = math.Mod(v, 1e6)
+                back := Convert(Convert(v, a, b), b, a)
+                return math.Abs(back-v) <= 1e-9*math.Max(1, math.Abs(v))
+            }
+            if err := quick.Check(f, nil); err != nil {
+                t.Errorf("%s -> %s -> %s: %v", a.Name, b.Name, a.Name, err)
+            }
+        }
+    }
+}
+
+func TestDelta(t *testing.T) {
+    d := CelsiusScale.Diff(30, 20)
+    if got := d.In(FahrenheitScale); !near(got.Value, 18) {
+        t.Errorf("%v = %v, want 18Δ°F", d, got)
+    }
+    if got := FahrenheitScale.Raise(50, d); !near(got, 68) {
+        t.Errorf("50°F + %v = %g°F, want 68°F", d, got)
+    }
+}
+
+func TestValid(t *testing.T) {
+    for _, s := range scales {
+        zero := s.FromKelvin(0)
+        if !s.Valid(zero) {
+            t.Errorf("absolute zero %g%s is not valid", zero, s.Symbol)
+        }
+        below := zero - 1
+        if s.Factor < 0 {
+            below = zero + 1
+        }
+        if s.Valid(below) {
+            t.Errorf("%g%s below absolute zero is valid", below, s.Symbol)
+        }
+        if got := s.Clamp(below); got != zero {
+            t.Errorf("Clamp(%g%s) = %g, want %g", below, s.Symbol, got, zero)
+        }
+    }
+    if _, err := NewCelsius(-300); err == nil {
+        t.Errorf("NewCelsius(-300) succeeded")
+    }
+    if _, err := CToFChecked(AbsoluteZeroC); err != nil {
+        t.Errorf("CToFChecked(%v): %v", AbsoluteZeroC, err)
+    }
+}
+
+func TestSet(t *testing.T) {
+    var c Celsius
+    if err := c.Set("212 °F"); err != nil || !near(float64(c), 100) {
+        t.Errorf("Set(212 °F) = %v, %v, want 100°C", c, err)
+    }
+    for _, s := range []string{"nanC", "InfC", "1_000C", "2.5E", "1e999K", "37.5", "37.5X"} {
+        if err := c.Set(s); err == nil {
+            t.Errorf("Set(%q) succeeded: %v", s, c)
+        }
+    }
+}
+
+//!-
@@ -0,0 +1,68 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "fmt"
+    "math/big"
+)
+
+// An Affine is the exact conversion v*Scale + Offset.
+type Affine struct {
+    Scale, Offset *big.Rat
+}
+
+// NewAffine returns the affine conversion with the given scale and offset,
+// each a decimal or a fraction like "5/9". It panics on malformed input and
+// is meant for constant conversions.
+func NewAffine(scale, offset string) *Affine {
+    a, err := ParseAffine(scale, offset)
+    if err != nil {
+        panic(err)
+    }
+    return a
+}
+
+// ParseAffine is like NewAffine but returns an error on malformed input.
+func ParseAffine(scale, offset string) (*Affine, error) {
+    s, ok := new(big.Rat).SetString(scale)
+    if !ok {
+        return nil, fmt.Errorf("invalid number %q", scale)
+    }
+    o, ok := new(big.Rat).SetString(offset)
+    if !ok {
+        return nil, fmt.Errorf("invalid number %q", offset)
+    }
+    return &Affine{s, o}, nil
+}
+
+// Apply returns v converted by a.
+func (a *Affine) Apply(v *big.Rat) *big.Rat {
+    r := new(big.Rat).Mul(v, a.Scale)
+    return r.Add(r, a.Offset)
+}
+
+// Inverse returns the conversion undoing a. The scale of a must not be zero.
+func (a *Affine) Inverse() *Affine {
+    s := new(big.Rat).Inv(a.Scale)
+    o := new(big.Rat).Mul(a.Offset, s)
+    return &Affine{s, o.Neg(o)}
+}
+
+// Then returns the conversion applying a, then b.
+func (a *Affine) Then(b *Affine) *Affine {
+    s := new(big.Rat).Mul(a.Scale, b.Scale)
+    return &Affine{s, b.Apply(a.Offset)}
+}
+
+// Float returns the float64 function approximating a.
+func (a *Affine) Float() func(float64) float64 {
+    scale, _ := a.Scale.Float64()
+    offset, _ := a.Offset.Float64()
+    return func(v float64) float64 { return v*scale + offset }
+}
+
+//!-
@@ -0,0 +1,198 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "encoding/json"
+    "fmt"
+    "main/lengthconv"
+    "main/tempconv"
+    "main/weightconv"
+    "math/big"
+    "strconv"
+)
+
+// Default is the registry of the built-in units.
+var Default = Builtin()
+
+// Builtin returns a registry holding the units of the tempconv, lengthconv
+// and weightconv packages, including their metric and customary unit tables,
+// common units of time, and some named compound units such as liters and
+// pascals.
+//
+// Each temperature scale also has a unit for temperature differences, such
+// as "delta celsius" or Δ°C, which converts without the offset of the scale.
+func Builtin() *Registry {
+    r := New()
+    units := []*Unit{
+        {Name: "celsius", Symbol: "°C", Aliases: []string{"c", "°c"}, Dimension: Temperature,
+            Value: func(v float64) fmt.Stringer { return tempconv.Celsius(v) }},
+        {Name: "kelvin", Symbol: "K", Aliases: []string{"k"}, Dimension: Temperature,
+            Value: func(v float64) fmt.Stringer { return tempconv.Kelvin(v) }},
+        {Name: "fahrenheit", Symbol: "°F", Aliases: []string{"f", "°f"}, Dimension: Temperature,
+            Value: func(v float64) fmt.Stringer { return tempconv.Fahrenheit(v) }},
+        {Name: "pound", Symbol: "lb", Aliases: []string{"lb", "lbs", "pounds"}, Dimension: Mass,
+            Value: func(v float64) fmt.Stringer { return weightconv.Pound(v) }},
+        {Name: "kilogram", Symbol: "kg", Aliases: []string{"kg", "kilograms"}, Dimension: Mass,
+            Value: func(v float64) fmt.Stringer { return weightconv.Kilogram(v) }},
+        {Name: "meter", Symbol: "m", Aliases: []string{"m", "meters", "metre", "metres"}, Dimension: Length,
+            Value: func(v float64) fmt.Stringer { return lengthconv.Meter(v) }},
+        {Name: "foot", Symbol: "ft", Aliases: []string{"ft", "feet"}, Dimension: Length,
+            Value: func(v float64) fmt.Stringer { return lengthconv.Foot(v) }},
+        {Name: "second", Symbol: "s", Aliases: []string{"s", "sec", "seconds"}, Dimension: Time},
+        {Name: "minute", Symbol: "min", Aliases: []string{"min", "minutes"}, Dimension: Time},
+        {Name: "hour", Symbol: "h", Aliases: []string{"h", "hr", "hours"}, Dimension: Time},
+    }
+    for _, u := range units {
+        if err := r.register(u); err != nil {
+            panic(err)
+        }
+    }
+    for _, name := range []string{"kelvin", "kilogram", "meter", "second"} {
+        u, _ := r.lookup(name)
+        r.bases[u.Dimension.String()] = u
+    }
+
+    conversions := []struct {
+        from, to string
+        fn       func(float64) float64
+        relErr   float64
+        exact    *Affine
+    }{
+        {"celsius", "fahrenheit", func(v float64) float64 { return float64(tempconv.CToF(tempconv.Celsius(v))) },
+            0, NewAffine("9/5", "32")},
+        {"celsius", "kelvin", func(v float64) float64 { return float64(tempconv.CToK(tempconv.Celsius(v))) },
+            0, NewAffine("1", "273.15")},
+        {"kelvin", "celsius", func(v float64) float64 { return float64(tempconv.KToC(tempconv.Kelvin(v))) },
+            0, NewAffine("1", "-273.15")},
+        {"fahrenheit", "celsius", func(v float64) float64 { return float64(tempconv.FToC(tempconv.Fahrenheit(v))) },
+            0, NewAffine("5/9", "-160/9")},
+        {"pound", "kilogram", func(v float64) float64 { return float64(weightconv.PToK(weightconv.Pound(v))) },
+            0, NewAffine("0.45359237", "0")},
+        {"kilogram", "pound", func(v float64) float64 { return float64(weightconv.KToP(weightconv.Kilogram(v))) },
+            0, NewAffine("0.45359237", "0").Inverse()},
+        {"meter", "foot", func(v float64) float64 { return float64(lengthconv.MToF(lengthconv.Meter(v))) },
+            0, NewAffine("0.3048", "0").Inverse()},
+        {"foot", "meter", func(v float64) float64 { return float64(lengthconv.FToM(lengthconv.Foot(v))) },
+            0, NewAffine("0.3048", "0")},
+        {"minute", "second", func(v float64) float64 { return v * 60 }, 0, NewAffine("60", "0")},
+        {"second", "minute", func(v float64) float64 { return v / 60 }, 0, NewAffine("1/60", "0")},
+        {"hour", "second", func(v float64) float64 { return v * 3600 }, 0, NewAffine("3600", "0")},
+        {"second", "hour", func(v float64) float64 { return v / 3600 }, 0, NewAffine("1/3600", "0")},
+    }
+    for _, c := range conversions {
+        if err := r.AddConversion(c.from, c.to, c.fn, c.relErr, c.exact); err != nil {
+            panic(err)
+        }
+    }
+
+    addTemperatureScales(r)
+
+    // The factors of the unit tables are written as exact decimals, which
+    // are recovered from the shortest representation of each float64.
+    var tables []Def
+    for _, u := range append(lengthconv.Metric(), lengthconv.Customary...) {
+        tables = append(tables, Def{Name: u.Name, Symbol: u.Symbol, Aliases: u.Aliases, Base: "meter",
+            Factor: json.Number(strconv.FormatFloat(u.Meters, 'g', -1, 64))})
+    }
+    for _, u := range weightconv.Metric() {
+        tables = append(tables, Def{Name: u.Name, Symbol: u.Symbol, Aliases: u.Aliases, Base: "kilogram",
+            Factor: json.Number(strconv.FormatFloat(u.Kilograms, 'g', -1, 64))})
+    }
+    // The customary weights are exact multiples of the pound, against which
+    // they are defined so that converting between them doesn't drift.
+    pound := big.NewRat(45359237, 100000000)
+    for _, u := range weightconv.Customary {
+        f, _ := new(big.Rat).SetString(strconv.FormatFloat(u.Kilograms, 'g', -1, 64))
+        tables = append(tables, Def{Name: u.Name, Symbol: u.Symbol, Aliases: u.Aliases, Base: "pound",
+            Factor: json.Number(f.Quo(f, pound).RatString())})
+    }
+    for _, d := range tables {
+        if err := r.define(d, false); err != nil {
+            panic(err)
+        }
+    }
+
+    derived := []Def{
+        {Name: "liter", Symbol: "L", Aliases: []string{"litre", "liters", "litres"}, Base: "m^3", Factor: "0.001"},
+        {Name: "gallon", Symbol: "gal", Aliases: []string{"gallons"}, Base: "m^3", Factor: "0.003785411784"},
+        {Name: "acre", Symbol: "ac", Aliases: []string{"acres"}, Base: "m^2", Factor: "4046.8564224"},
+        {Name: "mile per hour", Symbol: "mph", Base: "m/s", Factor: "0.44704"},
+        {Name: "pascal", Symbol: "Pa", Base: "kg/m/s^2", Factor: "1"},
+        {Name: "bar", Base: "kg/m/s^2", Factor: "100000"},
+        // One pound-force, 0.45359237 kg × 9.80665 m/s², per square inch.
+        {Name: "pound per square inch", Symbol: "psi", Base: "kg/m/s^2", Factor: "44482216152605/6451600000"},
+    }
+    for _, d := range derived {
+        if err := r.define(d, false); err != nil {
+            panic(err)
+        }
+    }
+    return r
+}
+
+// addTemperatureScales adds the remaining scales of tempconv and the units of
+// temperature differences.
+func addTemperatureScales(r *Registry) {
+    scales := []struct {
+        scale          tempconv.Scale
+        aliases        []string
+        factor, offset string // exact form of scale
+        value          func(float64) fmt.Stringer
+    }{
+        {tempconv.KelvinScale, nil, "1", "0", nil},
+        {tempconv.CelsiusScale, nil, "1", "273.15", nil},
+        {tempconv.FahrenheitScale, nil, "5/9", "45967/180", nil},
+        {tempconv.RankineScale, []string{"°r"}, "5/9", "0",
+            func(v float64) fmt.Stringer { return tempconv.Rankine(v) }},
+        {tempconv.ReaumurScale, []string{"reaumur", "°ré", "°re"}, "5/4", "273.15",
+            func(v float64) fmt.Stringer { return tempconv.Reaumur(v) }},
+        {tempconv.DelisleScale, []string{"°de"}, "-2/3", "373.15",
+            func(v float64) fmt.Stringer { return tempconv.Delisle(v) }},
+        {tempconv.NewtonScale, []string{"°n"}, "100/33", "273.15",
+            func(v float64) fmt.Stringer { return tempconv.Newton(v) }},
+        {tempconv.RomerScale, []string{"romer", "°rø"}, "40/21", "36241/140",
+            func(v float64) fmt.Stringer { return tempconv.Romer(v) }},
+    }
+    kelvin, _ := r.lookup("kelvin")
+    deltaKelvin := &Unit{Name: "delta kelvin", Symbol: "ΔK", Aliases: []string{"ΔK"},
+        Dimension: Temperature, IsDelta: true}
+    if err := r.register(deltaKelvin); err != nil {
+        panic(err)
+    }
+    kelvin.Delta = deltaKelvin
+    // A difference of 1 K is 1 K, which lets compound units like J/Δ°C
+    // resolve through kelvin.
+    r.link(deltaKelvin, kelvin, NewAffine("1", "0"), 0)
+
+    for _, sc := range scales {
+        s := sc.scale
+        u, ok := r.lookup(s.Name)
+        if !ok {
+            u = &Unit{Name: s.Name, Symbol: s.Symbol, Aliases: sc.aliases, Dimension: Temperature, Value: sc.value}
+            if err := r.register(u); err != nil {
+                panic(err)
+            }
+            r.addConversion(Conversion{From: u, To: kelvin,
+                Fn: func(v float64) float64 { return float64(s.ToKelvin(v)) }, Exact: NewAffine(sc.factor, sc.offset)})
+            r.addConversion(Conversion{From: kelvin, To: u,
+                Fn:    func(v float64) float64 { return s.FromKelvin(tempconv.Kelvin(v)) },
+                Exact: NewAffine(sc.factor, sc.offset).Inverse()})
+        }
+        if u == kelvin {
+            continue
+        }
+        d := &Unit{Name: "delta " + s.Name, Symbol: "Δ" + s.Symbol, Aliases: []string{"Δ" + s.Symbol},
+            Dimension: Temperature, IsDelta: true}
+        if err := r.register(d); err != nil {
+            panic(err)
+        }
+        u.Delta = d
+        r.link(d, deltaKelvin, NewAffine(sc.factor, "0"), 0)
+    }
+}
+
+//!-
@@ -0,0 +1,348 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "fmt"
+    "math"
+    "math/big"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Resolve returns the unit with the given name or alias, or else parses name
+// as a product of powers of known units, such as "ft^2", "km/h" or
+// "kg*m/s^2", and adds it to the registry. It returns an *UnknownUnitError
+// if name or a unit in it is unknown.
+func (r *Registry) Resolve(name string) (*Unit, error) {
+    if u, ok := r.Lookup(name); ok {
+        return u, nil
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.resolve(name)
+}
+
+// ResolveTemporary is like Resolve but adds nothing to the registry, neither
+// compound units nor the product of base units of a new dimension, so that
+// resolving the units of untrusted input doesn't make it grow with the
+// input. Paths from and to such a unit are found like for other units, but
+// it isn't known by its name afterwards.
+func (r *Registry) ResolveTemporary(name string) (*Unit, error) {
+    if u, ok := r.Lookup(name); ok {
+        return u, nil
+    }
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.compound(name, false)
+}
+
+func (r *Registry) resolve(name string) (*Unit, error) {
+    return r.compound(name, true)
+}
+
+// compound returns the unit name, parsing it as a compound unit if needed,
+// which is added to the registry if add is set. Otherwise, the conversions
+// between the unit and its base unit are kept in the links of the unit, and
+// the base unit of a new dimension is made for the unit alone.
+func (r *Registry) compound(name string, add bool) (*Unit, error) {
+    if u, ok := r.lookup(name); ok {
+        return u, nil
+    }
+    terms, err := r.parseUnitExpr(name)
+    if err != nil {
+        return nil, err
+    }
+    dim := make(Dimension)
+    s := one
+    for _, t := range terms {
+        f, err := r.factorToBase(t.unit)
+        if err != nil {
+            return nil, err
+        }
+        s = s.mul(f.pow(t.power))
+        dim = dim.Mul(t.unit.Dimension.Pow(t.power))
+    }
+    for _, n := range dim {
+        if n > maxPower || n < -maxPower {
+            return nil, fmt.Errorf("dimension %s of unit %q has a power out of range [-%d, %d]",
+                dim, name, maxPower, maxPower)
+        }
+    }
+    base, ok := r.bases[dim.String()]
+    if !ok && add {
+        base = r.siUnit(dim)
+    } else if !ok {
+        symbol := r.siSymbol(dim)
+        base = &Unit{Name: symbol, Symbol: symbol, Dimension: dim, reg: r}
+    }
+    if strings.EqualFold(name, base.Name) && (ok || add) {
+        return base, nil
+    }
+    u := &Unit{Name: name, Symbol: name, Dimension: base.Dimension, reg: r}
+    factor := s.factor
+    var exact, inverse *Affine
+    if s.exact != nil {
+        exact = &Affine{s.exact, new(big.Rat)}
+        inverse = exact.Inverse()
+    }
+    to := Conversion{From: u, To: base, Fn: func(v float64) float64 { return v * factor },
+        RelErr: s.relErr, Exact: exact}
+    from := Conversion{From: base, To: u, Fn: func(v float64) float64 { return v / factor },
+        RelErr: s.relErr, Exact: inverse}
+    if add {
+        r.names[strings.ToLower(name)] = u
+        r.addConversion(to)
+        r.addConversion(from)
+    } else {
+        u.links = []Conversion{to, from}
+    }
+    return u, nil
+}
+
+// Product returns the unit of the product of a quantity in unit a and the
+// n-th power of a quantity in unit b, e.g. m/s for a = m, b = s and n = -1.
+// The powers of the units a and b are made of are combined, so that the
+// quotient of m and m/s is s.
+func (r *Registry) Product(a, b *Unit, n int) (*Unit, error) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    var terms []unitTerm
+    for _, t := range r.unitTerms(a) {
+        terms = addTerm(terms, t)
+    }
+    for _, t := range r.unitTerms(b) {
+        terms = addTerm(terms, unitTerm{t.unit, t.power * n})
+    }
+    if len(terms) == 0 {
+        return r.siUnit(make(Dimension)), nil
+    }
+    return r.resolve(r.formatTerms(terms))
+}
+
+// unitTerms returns the powers of units u is the product of, which is u
+// alone unless it is a compound unit.
+func (r *Registry) unitTerms(u *Unit) []unitTerm {
+    if terms, err := r.parseUnitExpr(u.Name); err == nil {
+        return terms
+    }
+    return []unitTerm{{u, 1}}
+}
+
+// addTerm multiplies the product of terms by t.
+func addTerm(terms []unitTerm, t unitTerm) []unitTerm {
+    for i := range terms {
+        if terms[i].unit == t.unit {
+            terms[i].power += t.power
+            if terms[i].power == 0 {
+                terms = append(terms[:i], terms[i+1:]...)
+            }
+            return terms
+        }
+    }
+    return append(terms, t)
+}
+
+// formatTerms writes terms in the syntax of parseUnitExpr, like "kg*m/s^2",
+// or "s^-1" if there is nothing to divide.
+func (r *Registry) formatTerms(terms []unitTerm) string {
+    var num, den, all []string
+    for _, t := range terms {
+        name := t.unit.Symbol
+        if u, ok := r.lookup(name); !ok || u != t.unit {
+            name = t.unit.Name
+        }
+        if t.power > 0 {
+            num = append(num, power(name, t.power))
+        } else {
+            den = append(den, power(name, -t.power))
+        }
+        all = append(all, power(name, t.power))
+    }
+    if len(num) == 0 {
+        return strings.Join(all, "*")
+    }
+    return strings.Join(append([]string{strings.Join(num, "*")}, den...), "/")
+}
+
+// power writes the n-th power of unit name.
+func power(name string, n int) string {
+    if n == 1 {
+        return name
+    }
+    return name + "^" + strconv.Itoa(n)
+}
+
+// A scale is the factor of a conversion without offset.
+type scale struct {
+    factor float64
+    exact  *big.Rat // nil if unknown
+    relErr float64
+}
+
+var one = scale{1, big.NewRat(1, 1), 0}
+
+// mul returns the scale of converting by s, then by t.
+func (s scale) mul(t scale) scale {
+    r := scale{s.factor * t.factor, nil, s.relErr + t.relErr}
+    if s.exact != nil && t.exact != nil {
+        r.exact = new(big.Rat).Mul(s.exact, t.exact)
+    }
+    return r
+}
+
+// pow returns s applied n times, or its inverse -n times if n is negative.
+// It squares s repeatedly, so it takes O(log n) steps.
+func (s scale) pow(n int) scale {
+    if n < 0 {
+        n = -n
+        inv := scale{1 / s.factor, nil, s.relErr}
+        if s.exact != nil {
+            inv.exact = new(big.Rat).Inv(s.exact)
+        }
+        s = inv
+    }
+    r := one
+    for ; n > 0; n >>= 1 {
+        if n&1 == 1 {
+            r = r.mul(s)
+        }
+        if n > 1 {
+            s = s.mul(s)
+        }
+    }
+    return r
+}
+
+// factorToBase returns the scale of converting u into the base unit of its
+// dimension. Units with an offset such as celsius have no such scale.
+func (r *Registry) factorToBase(u *Unit) (scale, error) {
+    base, ok := r.bases[u.Dimension.String()]
+    if !ok {
+        return scale{}, fmt.Errorf("dimension %s of %s has no base unit", u.Dimension, u.Name)
+    }
+    path, ok := r.path(u, base, time.Time{})
+    if !ok {
+        return scale{}, fmt.Errorf("Can't convert %v to %v", u.Name, base.Name)
+    }
+    s := scale{path.Apply(1), nil, path.RelErr()}
+    exact, err := path.Exact()
+    if math.Abs(path.Apply(0)) > 1e-9 || err == nil && exact.Offset.Sign() != 0 {
+        return scale{}, fmt.Errorf("%s is not a multiple of %s and can't be part of a compound unit", u.Name, base.Name)
+    }
+    if err == nil {
+        s.exact = exact.Scale
+    }
+    return s, nil
+}
+
+// siUnit returns the product of base units with dimension dim, adding it to
+// the registry if needed. A base dimension without a base unit of its own,
+// such as one only part of the dimension of a loaded unit, is written by
+// name.
+func (r *Registry) siUnit(dim Dimension) *Unit {
+    if u, ok := r.bases[dim.String()]; ok {
+        return u
+    }
+    symbol := r.siSymbol(dim)
+    u := &Unit{Name: symbol, Symbol: symbol, Dimension: dim, reg: r}
+    r.names[strings.ToLower(symbol)] = u
+    r.bases[dim.String()] = u
+    return u
+}
+
+// siSymbol returns the symbol of the product of base units with dimension
+// dim, like "kg*m/s^2".
+func (r *Registry) siSymbol(dim Dimension) string {
+    return dim.Format(func(name string) string {
+        if b, ok := r.bases[name]; ok {
+            return b.Symbol
+        }
+        return name
+    })
+}
+
+// maxPower is the largest power of a unit in a compound unit, and of a base
+// dimension in its dimension, which keeps exact factors and the number of
+// dimensions small.
+const maxPower = 9
+
+type unitTerm struct {
+    unit  *Unit
+    power int
+}
+
+// parseUnitExpr parses units joined by '*', '·' or '/', each optionally
+// raised to an integer power with '^', '²' or '³'. A '/' only divides by the
+// unit right after it, so "kg/m/s^2" is kg·m⁻¹·s⁻².
+func (r *Registry) parseUnitExpr(s string) ([]unitTerm, error) {
+    var terms []unitTerm
+    sign := 1
+    start := 0
+    for i := 0; i <= len(s); i++ {
+        if i < len(s) && s[i] != '*' && s[i] != '/' && !strings.HasPrefix(s[i:], "·") {
+            continue
+        }
+        if strings.TrimSpace(s[start:i]) == "" {
+            return nil, fmt.Errorf("missing unit in %q", s)
+        }
+        t, err := r.parseUnitTerm(s[start:i])
+        if err != nil {
+            return nil, err
+        }
+        t.power *= sign
+        terms = append(terms, t)
+        if i == len(s) {
+            break
+        }
+        sign = 1
+        if s[i] == '/' {
+            sign = -1
+        }
+        if s[i] != '*' && s[i] != '/' {
+            i += len("·") - 1
+        }
+        start = i + 1
+    }
+    return terms, nil
+}
+
+func (r *Registry) parseUnitTerm(s string) (unitTerm, error) {
+    s = strings.TrimSpace(s)
+    name, power := s, 1
+    if i := strings.LastIndex(s, "^"); i >= 0 {
+        p, err := strconv.Atoi(strings.TrimSpace(s[i+1:]))
+        if err != nil {
+            return unitTerm{}, fmt.Errorf("bad power in unit %q", s)
+        }
+        if p > maxPower || p < -maxPower {
+            return unitTerm{}, fmt.Errorf("power of unit %q is out of range [-%d, %d]", s, maxPower, maxPower)
+        }
+        name, power = strings.TrimSpace(s[:i]), p
+    } else if strings.HasSuffix(s, "²") {
+        name, power = strings.TrimSuffix(s, "²"), 2
+    } else if strings.HasSuffix(s, "³") {
+        name, power = strings.TrimSuffix(s, "³"), 3
+    }
+    u, ok := r.lookup(name)
+    if !ok {
+        return unitTerm{}, &UnknownUnitError{name, r.unitNames()}
+    }
+    return unitTerm{u, power}, nil
+}
+
+// An UnknownUnitError reports a unit name that is not registered.
+type UnknownUnitError struct {
+    Name  string
+    Known []string // the names of the registered units
+}
+
+func (e *UnknownUnitError) Error() string {
+    return fmt.Sprintf("Unrecognized unit %q, available units are %v", e.Name, e.Known)
+}
+
+//!-
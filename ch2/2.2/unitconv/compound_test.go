@@ -0,0 +1,113 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "errors"
+    "math/big"
+    "testing"
+)
+
+func TestResolvePower(t *testing.T) {
+    r := Builtin()
+    // 1 unit is x^n of the base unit of its dimension.
+    tests := []struct {
+        unit string
+        x    string
+        n    int
+    }{
+        {"ft^3", "0.3048", 3},
+        {"ft^-2", "0.3048", -2},
+        {"in^9", "0.0254", 9},
+        {"mi/h", "0.44704", 1},
+    }
+    for _, test := range tests {
+        u, err := r.Resolve(test.unit)
+        if err != nil {
+            t.Errorf("Resolve(%q): %v", test.unit, err)
+            continue
+        }
+        base, _ := r.Base(u.Dimension)
+        got, err := r.ConvertExact(big.NewRat(1, 1), u, base)
+        if err != nil {
+            t.Errorf("%s to %s: %v", test.unit, base.Name, err)
+            continue
+        }
+        x, _ := new(big.Rat).SetString(test.x)
+        want := big.NewRat(1, 1)
+        for i := 0; i < test.n; i++ {
+            want.Mul(want, x)
+        }
+        for i := 0; i > test.n; i-- {
+            want.Quo(want, x)
+        }
+        if got.Cmp(want) != 0 {
+            t.Errorf("1 %s = %s %s, want %s^%d", test.unit, got.RatString(), base.Name, test.x, test.n)
+        }
+    }
+
+    for _, unit := range []string{"m^10", "m^-10", "m^30000000"} {
+        if _, err := r.Resolve(unit); err == nil {
+            t.Errorf("Resolve(%q) succeeded", unit)
+        }
+    }
+}
+
+func TestResolveTemporary(t *testing.T) {
+    r := Builtin()
+    before := len(r.Names())
+    pairs := []struct {
+        from, to string
+        want     float64
+    }{
+        {"g*km/s", "lb*ft/h", 3600 / 0.45359237 / 0.3048},
+        {"kg*m/s", "lb*ft/s", 1 / 0.45359237 / 0.3048}, // the name of the base unit
+        {"lb*ft/s", "kg*m/s", 0.45359237 * 0.3048},
+        {"kg^3*m^-7*s^5*K^2", "g^3*m^-7*s^5*K^2", 1e9},
+    }
+    for _, p := range pairs {
+        from, err := r.ResolveTemporary(p.from)
+        if err != nil {
+            t.Fatal(err)
+        }
+        to, err := r.ResolveTemporary(p.to)
+        if err != nil {
+            t.Fatal(err)
+        }
+        path, err := r.PathBetween(from, to)
+        if err != nil {
+            t.Errorf("%s -> %s: %v", p.from, p.to, err)
+            continue
+        }
+        if got := path.Apply(1); !near(got, p.want, 1, 1e-12) {
+            t.Errorf("1 %s = %g %s, want %g", p.from, got, p.to, p.want)
+        }
+    }
+    if _, ok := r.Lookup("g*km/s"); ok || len(r.Names()) != before {
+        t.Errorf("ResolveTemporary added units")
+    }
+
+    tests := []struct {
+        unit    string
+        unknown bool
+    }{
+        {"furlong", true},
+        {"m/furlong", true},
+        {"m//s", false},
+        {"m^x", false},
+        {"m^5*m^5", false},
+        {"celsius*m", false},
+    }
+    for _, test := range tests {
+        _, err := r.ResolveTemporary(test.unit)
+        var unknown *UnknownUnitError
+        if err == nil || errors.As(err, &unknown) != test.unknown {
+            t.Errorf("ResolveTemporary(%q): %v", test.unit, err)
+        }
+    }
+}
+
+//!-
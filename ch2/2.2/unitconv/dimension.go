@@ -0,0 +1,176 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// A Dimension maps base dimensions such as mass, length and time to their
+// exponents, e.g. {"length": 1, "time": -1} for speed. Zero exponents are
+// never stored, so the empty Dimension is dimensionless.
+type Dimension map[string]int
+
+// Base dimensions of the built-in units.
+var (
+    Mass        = Dimension{"mass": 1}
+    Length      = Dimension{"length": 1}
+    Time        = Dimension{"time": 1}
+    Temperature = Dimension{"temperature": 1}
+)
+
+// baseOrder is the order of base dimensions in String; others follow
+// alphabetically.
+var baseOrder = []string{"mass", "length", "time", "temperature"}
+
+// Mul returns the dimension of a product of quantities of dimension d and e.
+func (d Dimension) Mul(e Dimension) Dimension {
+    r := make(Dimension)
+    for name, n := range d {
+        r[name] = n
+    }
+    for name, n := range e {
+        if r[name] += n; r[name] == 0 {
+            delete(r, name)
+        }
+    }
+    return r
+}
+
+// Div returns the dimension of a quotient of quantities of dimension d and e.
+func (d Dimension) Div(e Dimension) Dimension {
+    return d.Mul(e.Pow(-1))
+}
+
+// Pow returns the dimension of a quantity of dimension d raised to n.
+func (d Dimension) Pow(n int) Dimension {
+    r := make(Dimension)
+    if n == 0 {
+        return r
+    }
+    for name, m := range d {
+        r[name] = m * n
+    }
+    return r
+}
+
+// Equal reports whether d and e are the same dimension.
+func (d Dimension) Equal(e Dimension) bool {
+    if len(d) != len(e) {
+        return false
+    }
+    for name, n := range d {
+        if e[name] != n {
+            return false
+        }
+    }
+    return true
+}
+
+// Base returns the name of d if it is a single base dimension.
+func (d Dimension) Base() (string, bool) {
+    for name, n := range d {
+        if len(d) == 1 && n == 1 {
+            return name, true
+        }
+    }
+    return "", false
+}
+
+// Names returns the base dimensions of d in display order.
+func (d Dimension) Names() []string {
+    var names []string
+    for name := range d {
+        names = append(names, name)
+    }
+    sort.Slice(names, func(i, j int) bool {
+        a, b := baseRank(names[i]), baseRank(names[j])
+        if a != b {
+            return a < b
+        }
+        return names[i] < names[j]
+    })
+    return names
+}
+
+func baseRank(name string) int {
+    for i, b := range baseOrder {
+        if b == name {
+            return i
+        }
+    }
+    return len(baseOrder)
+}
+
+// String formats d like "mass/length/time^2", or "1" if d is dimensionless.
+func (d Dimension) String() string {
+    return d.Format(func(name string) string { return name })
+}
+
+// Format is like String but writes each base dimension as symbol(name).
+func (d Dimension) Format(symbol func(name string) string) string {
+    var num, den []string
+    for _, name := range d.Names() {
+        s, n := symbol(name), d[name]
+        if n < 0 {
+            n = -n
+        }
+        if n != 1 {
+            s += "^" + strconv.Itoa(n)
+        }
+        if d[name] > 0 {
+            num = append(num, s)
+        } else {
+            den = append(den, s)
+        }
+    }
+    if len(num) == 0 {
+        num = []string{"1"}
+    }
+    return strings.Join(append([]string{strings.Join(num, "*")}, den...), "/")
+}
+
+// ParseDimension parses a dimension in the format of String. As there, a
+// '/' only divides by the term right after it.
+func ParseDimension(s string) (Dimension, error) {
+    d := make(Dimension)
+    for len(s) > 0 {
+        sign := 1
+        if s[0] == '/' {
+            sign = -1
+        }
+        if s[0] == '/' || s[0] == '*' {
+            s = s[1:]
+        }
+        term := s
+        if i := strings.IndexAny(s, "*/"); i >= 0 {
+            term, s = s[:i], s[i:]
+        } else {
+            s = ""
+        }
+        term = strings.TrimSpace(term)
+        name, n := term, 1
+        if i := strings.Index(term, "^"); i >= 0 {
+            p, err := strconv.Atoi(strings.TrimSpace(term[i+1:]))
+            if err != nil {
+                return nil, fmt.Errorf("bad exponent in dimension term %q", term)
+            }
+            name, n = strings.TrimSpace(term[:i]), p
+        }
+        if name == "" {
+            return nil, fmt.Errorf("empty term in dimension")
+        }
+        if name != "1" {
+            d = d.Mul(Dimension{name: n * sign})
+        }
+    }
+    return d, nil
+}
+
+//!-
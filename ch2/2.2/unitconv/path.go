@@ -0,0 +1,260 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "container/heap"
+    "fmt"
+    "math/big"
+    "time"
+)
+
+// Every conversion step costs hopCost, plus errorCost per unit of relative
+// error, so that an error of 1e-9 weighs as much as one extra step.
+const (
+    hopCost   = 1.0
+    errorCost = 1e9
+)
+
+// Cost is the weight of c in path searches.
+func (c Conversion) Cost() float64 {
+    return hopCost + errorCost*c.RelErr
+}
+
+// A Path is a chain of conversions.
+type Path []Conversion
+
+// Apply converts v along p.
+func (p Path) Apply(v float64) float64 {
+    for _, c := range p {
+        v = c.Fn(v)
+    }
+    return v
+}
+
+// ApplyExact converts v exactly along p. It fails if some step has no
+// exact form.
+func (p Path) ApplyExact(v *big.Rat) (*big.Rat, error) {
+    a, err := p.Exact()
+    if err != nil {
+        return nil, err
+    }
+    return a.Apply(v), nil
+}
+
+// Exact returns the exact conversion p performs.
+func (p Path) Exact() (*Affine, error) {
+    a := NewAffine("1", "0")
+    for _, c := range p {
+        if c.Exact == nil {
+            return nil, fmt.Errorf("no exact conversion from %s to %s", c.From.Name, c.To.Name)
+        }
+        a = a.Then(c.Exact)
+    }
+    return a, nil
+}
+
+// Cost returns the total cost of p.
+func (p Path) Cost() float64 {
+    cost := 0.0
+    for _, c := range p {
+        cost += c.Cost()
+    }
+    return cost
+}
+
+// RelErr returns the estimated relative error of p.
+func (p Path) RelErr() float64 {
+    relErr := 0.0
+    for _, c := range p {
+        relErr += c.RelErr
+    }
+    return relErr
+}
+
+// Path returns the cheapest chain of conversions from one unit to another.
+// Among chains of equal cost, the one found first in registration order wins,
+// so the result does not depend on map iteration. Conversions by rate use
+// the latest rate.
+func (r *Registry) Path(from, to *Unit) (Path, bool) {
+    return r.PathAt(from, to, time.Time{})
+}
+
+// PathAt is like Path but uses the rates in effect at time t, skipping
+// conversions without a rate at t. A zero t stands for the latest rates.
+func (r *Registry) PathAt(from, to *Unit, t time.Time) (Path, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    return r.path(from, to, t)
+}
+
+func (r *Registry) path(from, to *Unit, t time.Time) (Path, bool) {
+    prev, done := r.search(from, to, t)
+    if !done[to] {
+        return nil, false
+    }
+    return pathTo(prev, from, to), true
+}
+
+// PathsFrom returns the cheapest paths at time t from unit from to every
+// other named unit it can be converted to, in registration order.
+func (r *Registry) PathsFrom(from *Unit, t time.Time) []Path {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    prev, done := r.search(from, nil, t)
+    var paths []Path
+    for _, u := range r.units {
+        if u != from && done[u] && CheckConvertible(from, u) == nil {
+            paths = append(paths, pathTo(prev, from, u))
+        }
+    }
+    return paths
+}
+
+// linkedBase returns the base unit u is linked to, nil if none.
+func linkedBase(u *Unit) *Unit {
+    if len(u.links) == 0 {
+        return nil
+    }
+    return u.links[0].To
+}
+
+// search finds the cheapest conversions from unit from at time t, until it
+// reaches unit to, or all units if to is nil. It returns the last conversion
+// to each unit reached and the set of units whose path is final. The links
+// of from and to count as conversions too, and if both have a base unit of
+// their own of the same dimension, to is linked to that of from.
+func (r *Registry) search(from, to *Unit, t time.Time) (prev map[*Unit]Conversion, done map[*Unit]bool) {
+    links := make(map[*Unit][]Conversion)
+    for _, c := range from.links {
+        links[c.From] = append(links[c.From], c)
+    }
+    if to != nil && to != from {
+        fromBase, toBase := linkedBase(from), linkedBase(to)
+        for _, c := range to.links {
+            if fromBase != nil && toBase != nil && toBase != fromBase && toBase.Dimension.Equal(fromBase.Dimension) {
+                if c.From == toBase {
+                    c.From = fromBase
+                } else {
+                    c.To = fromBase
+                }
+            }
+            links[c.From] = append(links[c.From], c)
+        }
+    }
+    dist := map[*Unit]float64{from: 0}
+    prev = make(map[*Unit]Conversion)
+    done = make(map[*Unit]bool)
+    queue := &unitQueue{{from, 0, 0}}
+    seq := 1
+    for queue.Len() > 0 {
+        item := heap.Pop(queue).(queueItem)
+        if done[item.unit] {
+            continue
+        }
+        done[item.unit] = true
+        if item.unit == to {
+            break
+        }
+        convs := r.graph[item.unit]
+        if extra := links[item.unit]; len(extra) > 0 {
+            convs = append(convs[:len(convs):len(convs)], extra...)
+        }
+        for _, c := range convs {
+            c, ok := c.At(t)
+            if !ok {
+                continue
+            }
+            d := item.dist + c.Cost()
+            if old, ok := dist[c.To]; ok && old <= d {
+                continue
+            }
+            dist[c.To] = d
+            prev[c.To] = c
+            heap.Push(queue, queueItem{c.To, d, seq})
+            seq++
+        }
+    }
+    return prev, done
+}
+
+// pathTo follows the conversions of prev back from unit to to unit from.
+func pathTo(prev map[*Unit]Conversion, from, to *Unit) Path {
+    var path Path
+    for u := to; u != from; u = prev[u].From {
+        path = append(path, prev[u])
+    }
+    for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+        path[i], path[j] = path[j], path[i]
+    }
+    return path
+}
+
+// PathBetween is like Path but checks the dimensions of from and to first
+// and reports failures as errors.
+func (r *Registry) PathBetween(from, to *Unit) (Path, error) {
+    return r.PathBetweenAt(from, to, time.Time{})
+}
+
+// PathBetweenAt is like PathBetween but uses the rates in effect at time t.
+func (r *Registry) PathBetweenAt(from, to *Unit, t time.Time) (Path, error) {
+    if err := CheckConvertible(from, to); err != nil {
+        return nil, err
+    }
+    path, ok := r.PathAt(from, to, t)
+    if !ok {
+        if !t.IsZero() {
+            return nil, fmt.Errorf("Can't convert %v to %v as of %s", from.Name, to.Name, t.Format(time.RFC3339))
+        }
+        return nil, fmt.Errorf("Can't convert %v to %v", from.Name, to.Name)
+    }
+    return path, nil
+}
+
+// Convert converts v from one unit to another.
+func (r *Registry) Convert(v float64, from, to *Unit) (float64, error) {
+    path, err := r.PathBetween(from, to)
+    if err != nil {
+        return 0, err
+    }
+    return path.Apply(v), nil
+}
+
+// ConvertExact is like Convert but uses exact arithmetic.
+func (r *Registry) ConvertExact(v *big.Rat, from, to *Unit) (*big.Rat, error) {
+    path, err := r.PathBetween(from, to)
+    if err != nil {
+        return nil, err
+    }
+    return path.ApplyExact(v)
+}
+
+type queueItem struct {
+    unit *Unit
+    dist float64
+    seq  int // insertion order, to break ties deterministically
+}
+
+// unitQueue is a min-heap of queueItems ordered by distance.
+type unitQueue []queueItem
+
+func (q unitQueue) Len() int { return len(q) }
+func (q unitQueue) Less(i, j int) bool {
+    if q[i].dist != q[j].dist {
+        return q[i].dist < q[j].dist
+    }
+    return q[i].seq < q[j].seq
+}
+func (q unitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *unitQueue) Push(x interface{}) { *q = append(*q, x.(queueItem)) }
+func (q *unitQueue) Pop() interface{} {
+    old := *q
+    item := old[len(old)-1]
+    *q = old[:len(old)-1]
+    return item
+}
+
+//!-
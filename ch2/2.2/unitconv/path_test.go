@@ -0,0 +1,245 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "math"
+    "math/big"
+    "math/rand"
+    "testing"
+)
+
+// references are conversions with known results, given exactly.
+var references = []struct {
+    value, from, to, want string
+}{
+    {"100", "celsius", "fahrenheit", "212"},
+    {"100", "celsius", "kelvin", "373.15"},
+    {"212", "fahrenheit", "celsius", "100"},
+    {"0", "celsius", "fahrenheit", "32"},
+    {"-40", "celsius", "fahrenheit", "-40"},
+    {"-273.15", "celsius", "kelvin", "0"},
+    {"0", "kelvin", "fahrenheit", "-459.67"},
+    {"0", "kelvin", "rankine", "0"},
+    {"100", "celsius", "réaumur", "80"},
+    {"100", "celsius", "delisle", "0"},
+    {"100", "celsius", "newton", "33"},
+    {"100", "celsius", "rømer", "60"},
+    {"1", "delta celsius", "delta fahrenheit", "1.8"},
+    {"1", "foot", "meter", "0.3048"},
+    {"1", "inch", "centimeter", "2.54"},
+    {"1", "yard", "foot", "3"},
+    {"1", "mile", "foot", "5280"},
+    {"1", "nautical mile", "meter", "1852"},
+    {"1", "pound", "kilogram", "0.45359237"},
+    {"1", "stone", "pound", "14"},
+    {"1", "pound", "ounce", "16"},
+    {"1", "short ton", "pound", "2000"},
+    {"1", "long ton", "pound", "2240"},
+    {"1", "short ton", "kilogram", "907.18474"},
+    {"1", "tonne", "gram", "1000000"},
+    {"1", "gallon", "liter", "3.785411784"},
+    {"1", "acre", "yd^2", "4840"},
+    {"1", "mph", "km/h", "1.609344"},
+    {"1", "bar", "pascal", "100000"},
+    {"1", "hour", "second", "3600"},
+}
+
+// near reports whether got is within the relative tolerance tol of want, or
+// tol of scale, the magnitude of the numbers involved.
+func near(got, want, scale, tol float64) bool {
+    scale = math.Max(1, math.Max(scale, math.Abs(want)))
+    return math.Abs(got-want) <= tol*scale
+}
+
+// tolerance is the relative error allowed for a float64 result of path: a
+// few rounding errors per step.
+func tolerance(path Path) float64 {
+    return 1e-12 * float64(len(path)+1)
+}
+
+// TestReferences converts the references exactly and in floating point.
+func TestReferences(t *testing.T) {
+    r := Builtin()
+    for _, ref := range references {
+        from, err := r.Resolve(ref.from)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+        to, err := r.Resolve(ref.to)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+        path, err := r.PathBetween(from, to)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+        v, _ := new(big.Rat).SetString(ref.value)
+        want, _ := new(big.Rat).SetString(ref.want)
+        if got, err := path.ApplyExact(v); err != nil {
+            t.Errorf("%s %s to %s: %v", ref.value, from.Name, to.Name, err)
+        } else if got.Cmp(want) != 0 {
+            t.Errorf("%s %s is exactly %s %s, want %s", ref.value, from.Name, got.FloatString(12), to.Name, ref.want)
+        }
+        fv, _ := v.Float64()
+        fwant, _ := want.Float64()
+        if got := path.Apply(fv); !near(got, fwant, fv, tolerance(path)) {
+            t.Errorf("%s %s is %v %s, want %s", ref.value, from.Name, got, to.Name, ref.want)
+        }
+    }
+}
+
+// samples are the values converted there and back.
+var samples = []float64{0, 1, -1, -40, 100, 12345.678, 1e-6, 1e9}
+
+// TestRoundTrips converts samples between all pairs of convertible units
+// and back again.
+func TestRoundTrips(t *testing.T) {
+    r := Builtin()
+    all := r.Units()
+    for _, from := range all {
+        for _, to := range all {
+            if from == to || CheckConvertible(from, to) != nil {
+                continue
+            }
+            there, ok1 := r.Path(from, to)
+            back, ok2 := r.Path(to, from)
+            if !ok1 || !ok2 {
+                t.Errorf("no path between %s and %s", from.Name, to.Name)
+                continue
+            }
+            tol := tolerance(there) + tolerance(back)
+            for _, v := range samples {
+                mid := there.Apply(v)
+                if got := back.Apply(mid); !near(got, v, math.Abs(mid), tol) {
+                    t.Errorf("%g %s -> %g %s -> %g %s", v, from.Name, mid, to.Name, got, from.Name)
+                }
+            }
+            a, err1 := there.Exact()
+            b, err2 := back.Exact()
+            if err1 != nil || err2 != nil {
+                t.Errorf("%s <-> %s has no exact form", from.Name, to.Name)
+                continue
+            }
+            if id := a.Then(b); id.Scale.Cmp(big.NewRat(1, 1)) != 0 || id.Offset.Sign() != 0 {
+                t.Errorf("%s -> %s -> %s is exactly v*%s%+s, not v", from.Name, to.Name, from.Name,
+                    id.Scale.RatString(), id.Offset.RatString())
+            }
+        }
+    }
+}
+
+// checkTriple checks the paths between units a, b and c of the same
+// dimension: that a path from a to c exists if one via b does, costs no
+// more, and converts v like the path via b, in floating point and exactly.
+func checkTriple(t *testing.T, r *Registry, a, b, c *Unit, v float64) {
+    t.Helper()
+    ab, ok1 := r.Path(a, b)
+    bc, ok2 := r.Path(b, c)
+    ac, ok3 := r.Path(a, c)
+    if !ok1 || !ok2 {
+        return
+    }
+    if !ok3 {
+        t.Fatalf("no path from %s to %s, but one via %s", a.Name, c.Name, b.Name)
+    }
+    if a == c && len(ac) != 0 {
+        t.Errorf("path from %s to itself has %d steps", a.Name, len(ac))
+    }
+    if ac.Cost() > ab.Cost()+bc.Cost()+1e-9 {
+        t.Errorf("path from %s to %s costs %g, but %g via %s", a.Name, c.Name, ac.Cost(),
+            ab.Cost()+bc.Cost(), b.Name)
+    }
+    direct, mid := ac.Apply(v), ab.Apply(v)
+    via := bc.Apply(mid)
+    tol := tolerance(ac) + tolerance(ab) + tolerance(bc)
+    if !near(via, direct, math.Max(math.Abs(v), math.Abs(mid)), tol) {
+        t.Errorf("%g %s is %g %s, but %g via %s", v, a.Name, direct, c.Name, via, b.Name)
+    }
+    exact, err1 := ac.Exact()
+    exactAB, err2 := ab.Exact()
+    exactBC, err3 := bc.Exact()
+    if err1 == nil && err2 == nil && err3 == nil {
+        x := new(big.Rat).SetFloat64(v)
+        if got, want := exactBC.Apply(exactAB.Apply(x)), exact.Apply(x); got.Cmp(want) != 0 {
+            t.Errorf("%g %s is exactly %s %s, but %s via %s", v, a.Name, want.FloatString(12), c.Name,
+                got.FloatString(12), b.Name)
+        }
+    }
+}
+
+// byDimension groups the units of r by dimension, telling temperatures and
+// temperature differences apart.
+func byDimension(r *Registry) [][]*Unit {
+    index := make(map[string]int)
+    var groups [][]*Unit
+    for _, u := range r.Units() {
+        key := u.Dimension.String()
+        if u.IsDelta {
+            key += " difference"
+        }
+        i, ok := index[key]
+        if !ok {
+            i = len(groups)
+            index[key] = i
+            groups = append(groups, nil)
+        }
+        groups[i] = append(groups[i], u)
+    }
+    return groups
+}
+
+// TestConsistency picks random triples of units of the same dimension and
+// checks their paths with checkTriple.
+func TestConsistency(t *testing.T) {
+    r := Builtin()
+    groups := byDimension(r)
+    rnd := rand.New(rand.NewSource(1))
+    for i := 0; i < 10000; i++ {
+        us := groups[rnd.Intn(len(groups))]
+        a, b, c := us[rnd.Intn(len(us))], us[rnd.Intn(len(us))], us[rnd.Intn(len(us))]
+        v := rnd.NormFloat64() * math.Pow(10, float64(rnd.Intn(7)-3))
+        checkTriple(t, r, a, b, c, v)
+    }
+}
+
+// FuzzPath checks the paths of Registry.Path between units picked by the
+// fuzzer, like TestConsistency.
+func FuzzPath(f *testing.F) {
+    f.Add(uint8(0), uint16(0), uint16(1), uint16(2), 100.0)
+    f.Add(uint8(1), uint16(3), uint16(0), uint16(3), -40.0)
+    f.Add(uint8(2), uint16(5), uint16(7), uint16(11), 1e-3)
+    r := Builtin()
+    groups := byDimension(r)
+    f.Fuzz(func(t *testing.T, dim uint8, i, j, k uint16, v float64) {
+        if math.IsNaN(v) || math.IsInf(v, 0) || math.Abs(v) > 1e12 {
+            t.Skip()
+        }
+        us := groups[int(dim)%len(groups)]
+        a, b, c := us[int(i)%len(us)], us[int(j)%len(us)], us[int(k)%len(us)]
+        path, ok := r.Path(a, b)
+        if _, back := r.Path(b, a); ok != back {
+            t.Errorf("path from %s to %s: %t, but back: %t", a.Name, b.Name, ok, back)
+        }
+        from := a
+        for _, conv := range path {
+            if conv.From != from {
+                t.Fatalf("path from %s to %s converts from %s after reaching %s", a.Name, b.Name,
+                    conv.From.Name, from.Name)
+            }
+            from = conv.To
+        }
+        if ok && from != b {
+            t.Fatalf("path from %s to %s ends at %s", a.Name, b.Name, from.Name)
+        }
+        checkTriple(t, r, a, b, c, v)
+    })
+}
+
+//!-
@@ -0,0 +1,71 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "main/tempconv"
+    "math/big"
+)
+
+// absoluteZero returns absolute zero in the temperature unit u, exactly if
+// possible. u must be convertible to kelvin.
+func (u *Unit) absoluteZero() (*big.Rat, float64) {
+    r := u.registry()
+    kelvin, _ := r.Base(Temperature)
+    path, _ := r.Path(kelvin, u)
+    exact, err := path.ApplyExact(new(big.Rat))
+    if err != nil {
+        exact = nil
+    }
+    return exact, path.Apply(0)
+}
+
+// belowZero reports whether v in u is a temperature below absolute zero.
+func (u *Unit) belowZero(v float64) bool {
+    if u.IsDelta || !u.Dimension.Equal(Temperature) {
+        return false
+    }
+    r := u.registry()
+    kelvin, _ := r.Base(Temperature)
+    k, err := r.Convert(v, u, kelvin)
+    return err == nil && k < -1e-9
+}
+
+// CheckPhysical returns a *tempconv.AbsoluteZeroError if v in u is a
+// temperature below absolute zero.
+func (u *Unit) CheckPhysical(v float64) error {
+    if u.belowZero(v) {
+        return &tempconv.AbsoluteZeroError{Temp: Quantity{v, u}}
+    }
+    return nil
+}
+
+// ClampPhysical returns v, or absolute zero in u if v is a temperature
+// below it.
+func (u *Unit) ClampPhysical(v float64) float64 {
+    if !u.belowZero(v) {
+        return v
+    }
+    exact, zero := u.absoluteZero()
+    if exact != nil {
+        zero, _ = exact.Float64()
+    }
+    return zero
+}
+
+// ClampPhysicalRat is like ClampPhysical but for exact values.
+func (u *Unit) ClampPhysicalRat(v *big.Rat) *big.Rat {
+    f, _ := v.Float64()
+    if !u.belowZero(f) {
+        return v
+    }
+    if zero, _ := u.absoluteZero(); zero != nil {
+        return zero
+    }
+    return v
+}
+
+//!-
@@ -0,0 +1,44 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "errors"
+    "main/tempconv"
+    "testing"
+)
+
+func TestPhysical(t *testing.T) {
+    r := loadUnits(t)
+    // Absolute zero is -394.15/14 GM, about -28.15 GM.
+    tests := []struct {
+        unit       string
+        v, clamped float64
+    }{
+        {"celsius", -300, -273.15},
+        {"fahrenheit", -500, -459.67},
+        {"kelvin", -1, 0},
+        {"gas mark", -30, -394.15 / 14},
+        {"delta celsius", -300, -300},
+        {"foot", -1, -1},
+    }
+    for _, test := range tests {
+        u, _ := r.Lookup(test.unit)
+        err := u.CheckPhysical(test.v)
+        var zeroErr *tempconv.AbsoluteZeroError
+        if below := test.clamped != test.v; below != errors.As(err, &zeroErr) {
+            t.Errorf("CheckPhysical(%g %s) = %v", test.v, test.unit, err)
+        }
+        if got := u.ClampPhysical(test.v); !near(got, test.clamped, 1, 1e-12) {
+            t.Errorf("ClampPhysical(%g %s) = %g, want %g", test.v, test.unit, got, test.clamped)
+        }
+        if err := u.CheckPhysical(test.clamped); err != nil {
+            t.Errorf("CheckPhysical(%g %s): %v", test.clamped, test.unit, err)
+        }
+    }
+}
+
+//!-
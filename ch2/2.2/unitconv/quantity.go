@@ -0,0 +1,187 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "main/metric"
+    "strconv"
+    "strings"
+)
+
+// A Quantity is a value measured in some unit.
+type Quantity struct {
+    Value float64
+    Unit  *Unit
+}
+
+// Convert returns q expressed in unit to.
+func (q Quantity) Convert(to *Unit) (Quantity, error) {
+    v, err := q.Unit.registry().Convert(q.Value, q.Unit, to)
+    if err != nil {
+        return Quantity{}, err
+    }
+    return Quantity{v, to}, nil
+}
+
+// Add returns q+p in the unit of q. p must have the same dimension as q.
+// A temperature may be raised by a temperature difference, but two
+// temperatures can't be added.
+func (q Quantity) Add(p Quantity) (Quantity, error) {
+    to := q.Unit
+    if q.Unit.Delta != nil && p.Unit.IsDelta {
+        to = q.Unit.Delta
+    } else if q.Unit.Delta != nil && p.Unit.Delta != nil {
+        return Quantity{}, fmt.Errorf("can't add temperatures %s and %s", q, p)
+    }
+    p, err := p.Convert(to)
+    if err != nil {
+        return Quantity{}, err
+    }
+    return Quantity{q.Value + p.Value, q.Unit}, nil
+}
+
+// Sub returns q-p in the unit of q. p must have the same dimension as q.
+// The difference of two temperatures is a temperature difference, like Δ°C.
+func (q Quantity) Sub(p Quantity) (Quantity, error) {
+    if q.Unit.Delta != nil && p.Unit.Delta != nil {
+        p, err := p.Convert(q.Unit)
+        if err != nil {
+            return Quantity{}, err
+        }
+        return Quantity{q.Value - p.Value, q.Unit.Delta}, nil
+    }
+    p = Quantity{-p.Value, p.Unit}
+    return q.Add(p)
+}
+
+// Mul returns q*p in the product of their units, e.g. "ft*lb". Neither unit
+// may have an offset, like celsius does.
+func (q Quantity) Mul(p Quantity) (Quantity, error) {
+    u, err := q.Unit.registry().Product(q.Unit, p.Unit, 1)
+    if err != nil {
+        return Quantity{}, err
+    }
+    return Quantity{q.Value * p.Value, u}, nil
+}
+
+// Div returns q/p in the quotient of their units, e.g. "m/s". Neither unit
+// may have an offset, like celsius does.
+func (q Quantity) Div(p Quantity) (Quantity, error) {
+    u, err := q.Unit.registry().Product(q.Unit, p.Unit, -1)
+    if err != nil {
+        return Quantity{}, err
+    }
+    return Quantity{q.Value / p.Value, u}, nil
+}
+
+// Scale returns q multiplied by the dimensionless f.
+func (q Quantity) Scale(f float64) Quantity {
+    return Quantity{q.Value * f, q.Unit}
+}
+
+func (q Quantity) String() string {
+    if q.Unit == nil {
+        return strconv.FormatFloat(q.Value, 'g', -1, 64)
+    }
+    return q.Unit.Format(q.Value)
+}
+
+// Format implements fmt.Formatter, so that the floating-point verbs format
+// the value with their precision, e.g. "%.2f" gives "3.28 ft".
+func (q Quantity) Format(f fmt.State, verb rune) {
+    switch verb {
+    case 'e', 'E', 'f', 'F', 'g', 'G':
+        format := "%"
+        if p, ok := f.Precision(); ok {
+            format += "." + strconv.Itoa(p)
+        }
+        num := fmt.Sprintf(format+string(verb), q.Value)
+        if q.Unit != nil {
+            num = q.Unit.FormatNumber(num)
+        }
+        io.WriteString(f, num)
+    default:
+        io.WriteString(f, q.String())
+    }
+}
+
+// MarshalText formats q like "1.5 ft", which UnmarshalText parses back.
+func (q Quantity) MarshalText() ([]byte, error) {
+    if q.Unit == nil {
+        return nil, fmt.Errorf("quantity %g has no unit", q.Value)
+    }
+    return []byte(strconv.FormatFloat(q.Value, 'g', -1, 64) + " " + q.Unit.Symbol), nil
+}
+
+// UnmarshalText parses a number followed by a unit, such as "1.5 ft" or
+// "37.5°C", resolving the unit in the registry of q.Unit, or Default.
+func (q *Quantity) UnmarshalText(text []byte) error {
+    r := Default
+    if q.Unit != nil {
+        r = q.Unit.registry()
+    }
+    p, err := r.ParseQuantity(string(text))
+    if err != nil {
+        return err
+    }
+    *q = p
+    return nil
+}
+
+type jsonQuantity struct {
+    Value float64 `json:"value"`
+    Unit  string  `json:"unit"`
+}
+
+// MarshalJSON encodes q as {"value": 1.5, "unit": "ft"}.
+func (q Quantity) MarshalJSON() ([]byte, error) {
+    if q.Unit == nil {
+        return nil, fmt.Errorf("quantity %g has no unit", q.Value)
+    }
+    return json.Marshal(jsonQuantity{q.Value, q.Unit.Symbol})
+}
+
+// UnmarshalJSON decodes the format of MarshalJSON, or a string in the
+// format of MarshalText.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+    var text string
+    if err := json.Unmarshal(data, &text); err == nil {
+        return q.UnmarshalText([]byte(text))
+    }
+    var jq jsonQuantity
+    if err := json.Unmarshal(data, &jq); err != nil {
+        return err
+    }
+    r := Default
+    if q.Unit != nil {
+        r = q.Unit.registry()
+    }
+    u, err := r.Resolve(jq.Unit)
+    if err != nil {
+        return err
+    }
+    *q = Quantity{jq.Value, u}
+    return nil
+}
+
+// ParseQuantity parses a number followed by a unit, such as "1.5 ft", with
+// the rules of metric.ParseQuantity for the number.
+func (r *Registry) ParseQuantity(s string) (Quantity, error) {
+    v, unit, err := metric.ParseQuantity(s)
+    if err != nil {
+        return Quantity{}, fmt.Errorf("invalid quantity %q: %v", strings.TrimSpace(s), err)
+    }
+    u, err := r.Resolve(unit)
+    if err != nil {
+        return Quantity{}, err
+    }
+    return Quantity{v, u}, nil
+}
+
+//!-
@@ -0,0 +1,65 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import "testing"
+
+func TestMulDiv(t *testing.T) {
+    r := Builtin()
+    q := func(v float64, unit string) Quantity {
+        u, err := r.Resolve(unit)
+        if err != nil {
+            t.Fatal(err)
+        }
+        return Quantity{v, u}
+    }
+    tests := []struct {
+        op        string
+        q, p      Quantity
+        value     float64
+        unit, dim string
+    }{
+        {"*", q(2, "ft"), q(3, "lb"), 6, "ft*lb", "mass*length"},
+        {"/", q(6, "m"), q(2, "s"), 3, "m/s", "length/time"},
+        {"/", q(6, "m"), q(2, "m/s"), 3, "s", "time"},
+        {"*", q(6, "m/s"), q(2, "s"), 12, "m", "length"},
+        {"*", q(2, "kg"), q(3, "m/s^2"), 6, "kg*m/s^2", "mass*length/time^2"},
+        {"/", q(1, "s"), q(4, "m*s^2"), 0.25, "s^-1*m^-1", "1/length/time"},
+        {"/", q(6, "m"), q(2, "m"), 3, "1", "1"},
+    }
+    for _, test := range tests {
+        var got Quantity
+        var err error
+        if test.op == "*" {
+            got, err = test.q.Mul(test.p)
+        } else {
+            got, err = test.q.Div(test.p)
+        }
+        if err != nil {
+            t.Errorf("%v %s %v: %v", test.q, test.op, test.p, err)
+            continue
+        }
+        if got.Value != test.value || got.Unit.Symbol != test.unit || got.Unit.Dimension.String() != test.dim {
+            t.Errorf("%v %s %v = %v [%s], want %g %s [%s]", test.q, test.op, test.p, got, got.Unit.Dimension,
+                test.value, test.unit, test.dim)
+        }
+    }
+}
+
+func TestParseQuantity(t *testing.T) {
+    r := Builtin()
+    q, err := r.ParseQuantity(" 1.5e3 ft ")
+    if err != nil || q.Value != 1500 || q.Unit.Name != "foot" {
+        t.Errorf("ParseQuantity(1.5e3 ft) = %v, %v; want 1500 ft", q, err)
+    }
+    for _, s := range []string{"NaN ft", "Inf m", "0x10 m", "1_000 m", "2.5E m", "1e999 m", "ft", "3 furlongs"} {
+        if q, err := r.ParseQuantity(s); err == nil {
+            t.Errorf("ParseQuantity(%q) = %v, want an error", s, q)
+        }
+    }
+}
+
+//!-
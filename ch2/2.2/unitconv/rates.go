@@ -0,0 +1,190 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "math/big"
+    "os"
+    "sort"
+    "time"
+)
+
+// A Rate is the factor of a conversion from the time it took effect.
+type Rate struct {
+    Time   time.Time
+    Factor *big.Rat
+}
+
+// Rates is the history of the factor of a conversion, such as an exchange
+// rate, ordered by time.
+type Rates struct {
+    list []Rate
+}
+
+// add records rate, replacing a rate of the same time.
+func (rs *Rates) add(rate Rate) {
+    i := sort.Search(len(rs.list), func(i int) bool { return !rs.list[i].Time.Before(rate.Time) })
+    if i < len(rs.list) && rs.list[i].Time.Equal(rate.Time) {
+        rs.list[i] = rate
+        return
+    }
+    rs.list = append(rs.list, Rate{})
+    copy(rs.list[i+1:], rs.list[i:])
+    rs.list[i] = rate
+}
+
+// At returns the rate in effect at time t, or the latest rate if t is zero.
+// It reports false if there is no rate before t.
+func (rs *Rates) At(t time.Time) (Rate, bool) {
+    if len(rs.list) == 0 {
+        return Rate{}, false
+    }
+    if t.IsZero() {
+        return rs.list[len(rs.list)-1], true
+    }
+    i := sort.Search(len(rs.list), func(i int) bool { return rs.list[i].Time.After(t) })
+    if i == 0 {
+        return Rate{}, false
+    }
+    return rs.list[i-1], true
+}
+
+// At returns c with the rate in effect at time t, or the latest rate if t is
+// zero. Conversions without rates are returned as they are. It reports false
+// if c has no rate at t.
+func (c Conversion) At(t time.Time) (Conversion, bool) {
+    if c.Rates == nil {
+        return c, true
+    }
+    rate, ok := c.Rates.At(t)
+    if !ok {
+        return c, false
+    }
+    factor, _ := rate.Factor.Float64()
+    c.Fn = func(v float64) float64 { return v * factor }
+    c.Exact = &Affine{rate.Factor, new(big.Rat)}
+    c.Since = rate.Time
+    return c, true
+}
+
+// AddRate records that from time t, a value of unit from is factor times
+// that value in unit to. The first rate between two units adds conversions
+// between them in both directions.
+func (r *Registry) AddRate(from, to string, t time.Time, factor *big.Rat) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    f, ok := r.lookup(from)
+    if !ok {
+        return fmt.Errorf("unknown unit %q", from)
+    }
+    u, ok := r.lookup(to)
+    if !ok {
+        return fmt.Errorf("unknown unit %q", to)
+    }
+    if err := CheckConvertible(f, u); err != nil {
+        return err
+    }
+    if factor.Sign() <= 0 {
+        return fmt.Errorf("rate from %s to %s must be positive", f.Name, u.Name)
+    }
+    for _, c := range []struct {
+        from, to *Unit
+        factor   *big.Rat
+    }{{f, u, factor}, {u, f, new(big.Rat).Inv(factor)}} {
+        rates, ok := r.rates[[2]*Unit{c.from, c.to}]
+        if !ok {
+            rates = new(Rates)
+            r.rates[[2]*Unit{c.from, c.to}] = rates
+            r.addConversion(Conversion{From: c.from, To: c.to, Rates: rates})
+        }
+        rates.add(Rate{t, c.factor})
+    }
+    return nil
+}
+
+// ParseTime parses a date like 2006-01-02 or a time in RFC 3339 format.
+func ParseTime(s string) (time.Time, error) {
+    if t, err := time.Parse("2006-01-02", s); err == nil {
+        return t, nil
+    }
+    if t, err := time.Parse(time.RFC3339, s); err == nil {
+        return t, nil
+    }
+    return time.Time{}, fmt.Errorf("invalid time %q, want a date like 2006-01-02 or an RFC 3339 time", s)
+}
+
+// A RateDef is a rate in a rates file.
+type RateDef struct {
+    From string      `json:"from"`
+    To   string      `json:"to"`
+    Time string      `json:"time"`
+    Rate json.Number `json:"rate"`
+}
+
+// LoadRates reads units and their rates in JSON from in, like
+//
+//     {"units": [
+//         {"name": "euro", "symbol": "EUR", "dimension": "currency"},
+//         {"name": "US dollar", "symbol": "USD", "dimension": "currency"}
+//      ],
+//      "rates": [
+//         {"from": "US dollar", "to": "euro", "time": "2024-01-02", "rate": 0.9127},
+//         {"from": "US dollar", "to": "euro", "time": "2024-01-03", "rate": 0.9146}
+//     ]}
+//
+// The units are defined as in Load, except that a unit without a base unit
+// may join a dimension that has one already, to be converted by rates only.
+func (r *Registry) LoadRates(in io.Reader) error {
+    var file struct {
+        Units []Def     `json:"units"`
+        Rates []RateDef `json:"rates"`
+    }
+    dec := json.NewDecoder(in)
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&file); err != nil {
+        return err
+    }
+    r.mu.Lock()
+    for _, d := range file.Units {
+        if err := r.define(d, true); err != nil {
+            r.mu.Unlock()
+            return err
+        }
+    }
+    r.mu.Unlock()
+    for i, d := range file.Rates {
+        t, err := ParseTime(d.Time)
+        if err != nil {
+            return fmt.Errorf("rate %d: %v", i+1, err)
+        }
+        factor, ok := new(big.Rat).SetString(string(d.Rate))
+        if !ok {
+            return fmt.Errorf("rate %d: invalid number %q", i+1, d.Rate)
+        }
+        if err := r.AddRate(d.From, d.To, t, factor); err != nil {
+            return fmt.Errorf("rate %d: %v", i+1, err)
+        }
+    }
+    return nil
+}
+
+// LoadRatesFile is like LoadRates but reads the named file.
+func (r *Registry) LoadRatesFile(filename string) error {
+    f, err := os.Open(filename)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    if err := r.LoadRates(f); err != nil {
+        return fmt.Errorf("%s: %v", filename, err)
+    }
+    return nil
+}
+
+//!-
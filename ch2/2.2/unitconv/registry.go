@@ -0,0 +1,385 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+// Package unitconv describes units of measurement and converts quantities
+// between them along a graph of direct conversions, kept in a Registry.
+//
+// Besides the built-in units of tempconv, lengthconv and weightconv, units can
+// be loaded from a JSON file such as
+//
+//     {"units": [
+//         {"name": "fathom", "symbol": "ftm", "aliases": ["fathoms"],
+//          "dimension": "length", "base": "foot", "factor": 6},
+//         {"name": "gas mark", "symbol": "GM", "dimension": "temperature",
+//          "base": "celsius", "factor": 14, "offset": 121}
+//     ]}
+//
+// A value v of a loaded unit equals v*factor+offset in its base unit, which
+// must be registered before it and have the same dimension. The base unit may
+// also be a compound unit such as "kg/m/s^2". A unit without a base unit
+// starts a new dimension and becomes its base unit. A unit based on a
+// temperature scale is a scale too, with a unit of differences like
+// "delta gas mark", and one based on a temperature difference is a
+// difference.
+//
+// A Registry is safe for concurrent use by multiple goroutines.
+package unitconv
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "math/big"
+    "os"
+    "strings"
+    "sync"
+    "time"
+)
+
+// A Unit is a named unit of measurement.
+type Unit struct {
+    Name      string
+    Symbol    string
+    Aliases   []string
+    Dimension Dimension
+    // Value wraps a number of this unit into its Go type, e.g. tempconv.Celsius.
+    // It is nil for units loaded from a file.
+    Value func(float64) fmt.Stringer
+    // Delta is the unit of differences of temperatures in this unit, and
+    // IsDelta reports whether this is such a unit, like Δ°C.
+    Delta   *Unit
+    IsDelta bool
+
+    reg   *Registry    // the registry u belongs to
+    links []Conversion // conversions of a unit from ResolveTemporary
+}
+
+// Format formats v followed by the symbol of u.
+func (u *Unit) Format(v float64) string {
+    if u.Value != nil {
+        return u.Value(v).String()
+    }
+    return fmt.Sprintf("%g %s", v, u.Symbol)
+}
+
+// FormatRat is like Format but formats v to the given significant digits.
+func (u *Unit) FormatRat(v *big.Rat, digits int) string {
+    return u.FormatNumber(new(big.Float).SetPrec(256).SetRat(v).Text('g', digits))
+}
+
+// FormatNumber appends the symbol of u to the already formatted number num.
+func (u *Unit) FormatNumber(num string) string {
+    if u.Value != nil {
+        // Reuse the suffix of the Go type, e.g. "°C" or " ft".
+        return num + strings.TrimPrefix(u.Value(0).String(), "0")
+    }
+    return num + " " + u.Symbol
+}
+
+// registry returns the registry of u, or Default for units created outside
+// of a registry.
+func (u *Unit) registry() *Registry {
+    if u.reg != nil {
+        return u.reg
+    }
+    return Default
+}
+
+// A Conversion converts a value of unit From into unit To.
+type Conversion struct {
+    From, To *Unit
+    Fn       func(float64) float64
+    RelErr   float64 // estimated relative error of Fn, 0 if exact
+    Exact    *Affine // exact form of Fn, nil if unknown
+    // Rates holds the changing factor of a conversion by rate, for which Fn
+    // and Exact are set by At. Since is when the rate of At took effect.
+    Rates *Rates
+    Since time.Time
+}
+
+// A Registry holds units and the direct conversions between them.
+//
+// Its exported methods lock mu; the unexported ones expect the caller to
+// hold it.
+type Registry struct {
+    mu    sync.RWMutex
+    units []*Unit          // named units, in registration order
+    bases map[string]*Unit // base unit of each dimension, by Dimension.String
+    names map[string]*Unit // lower-cased names and aliases
+    graph map[*Unit][]Conversion
+    rates map[[2]*Unit]*Rates // rates by from and to unit
+}
+
+func New() *Registry {
+    return &Registry{
+        bases: make(map[string]*Unit),
+        names: make(map[string]*Unit),
+        graph: make(map[*Unit][]Conversion),
+        rates: make(map[[2]*Unit]*Rates),
+    }
+}
+
+// Units returns the named units in registration order.
+func (r *Registry) Units() []*Unit {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    return append([]*Unit(nil), r.units...)
+}
+
+// Base returns the base unit of dimension dim.
+func (r *Registry) Base(dim Dimension) (*Unit, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    u, ok := r.bases[dim.String()]
+    return u, ok
+}
+
+// Names returns the names of the units in registration order.
+func (r *Registry) Names() []string {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    return r.unitNames()
+}
+
+func (r *Registry) unitNames() []string {
+    var names []string
+    for _, u := range r.units {
+        names = append(names, u.Name)
+    }
+    return names
+}
+
+// Lookup returns the unit with the given name or alias, ignoring case.
+func (r *Registry) Lookup(name string) (*Unit, bool) {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+    return r.lookup(name)
+}
+
+func (r *Registry) lookup(name string) (*Unit, bool) {
+    u, ok := r.names[strings.ToLower(name)]
+    return u, ok
+}
+
+// Register adds u to r. It fails if the name or one of the aliases is taken.
+func (r *Registry) Register(u *Unit) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.register(u)
+}
+
+func (r *Registry) register(u *Unit) error {
+    if u.Name == "" {
+        return fmt.Errorf("unit has no name")
+    }
+    if u.Symbol == "" {
+        u.Symbol = u.Name
+    }
+    keys := append([]string{u.Name}, u.Aliases...)
+    for i, key := range keys {
+        key = strings.ToLower(key)
+        if _, ok := r.names[key]; ok {
+            return fmt.Errorf("unit name %q is already registered", key)
+        }
+        for _, prev := range keys[:i] {
+            if strings.ToLower(prev) == key {
+                return fmt.Errorf("unit %q lists %q twice", u.Name, key)
+            }
+        }
+    }
+    for _, key := range keys {
+        r.names[strings.ToLower(key)] = u
+    }
+    u.reg = r
+    r.units = append(r.units, u)
+    return nil
+}
+
+func (r *Registry) addConversion(c Conversion) {
+    r.graph[c.From] = append(r.graph[c.From], c)
+}
+
+// link adds the conversions between u and base, where v u is v*a base.
+func (r *Registry) link(u, base *Unit, a *Affine, relErr float64) {
+    factor, _ := a.Scale.Float64()
+    offset, _ := a.Offset.Float64()
+    r.addConversion(Conversion{From: u, To: base, Fn: func(v float64) float64 { return v*factor + offset },
+        RelErr: relErr, Exact: a})
+    r.addConversion(Conversion{From: base, To: u, Fn: func(v float64) float64 { return (v - offset) / factor },
+        RelErr: relErr, Exact: a.Inverse()})
+}
+
+// AddConversion registers fn as the conversion from unit from to unit to.
+// relErr estimates the relative error fn introduces, e.g. by a truncated
+// factor, and exact, if not nil, is the exact conversion fn approximates.
+func (r *Registry) AddConversion(from, to string, fn func(float64) float64, relErr float64, exact *Affine) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    f, ok := r.lookup(from)
+    if !ok {
+        return fmt.Errorf("unknown unit %q", from)
+    }
+    t, ok := r.lookup(to)
+    if !ok {
+        return fmt.Errorf("unknown unit %q", to)
+    }
+    if err := CheckConvertible(f, t); err != nil {
+        return err
+    }
+    r.addConversion(Conversion{From: f, To: t, Fn: fn, RelErr: relErr, Exact: exact})
+    return nil
+}
+
+// CheckConvertible reports an error naming both dimensions if from and to
+// measure different things, or if one is a temperature and the other a
+// temperature difference.
+func CheckConvertible(from, to *Unit) error {
+    if !from.Dimension.Equal(to.Dimension) {
+        return fmt.Errorf("can't convert %s [%s] to %s [%s]", from.Name, from.Dimension, to.Name, to.Dimension)
+    }
+    if from.IsDelta && to.Delta != nil {
+        return fmt.Errorf("can't convert temperature difference %s to temperature %s", from.Name, to.Name)
+    }
+    if from.Delta != nil && to.IsDelta {
+        return fmt.Errorf("can't convert temperature %s to temperature difference %s", from.Name, to.Name)
+    }
+    return nil
+}
+
+// A Def is the definition of a unit in a units file.
+type Def struct {
+    Name      string      `json:"name"`
+    Symbol    string      `json:"symbol"`
+    Aliases   []string    `json:"aliases"`
+    Dimension string      `json:"dimension"`
+    Base      string      `json:"base"`
+    Factor    json.Number `json:"factor"`
+    Offset    json.Number `json:"offset"`
+}
+
+// Define validates d and registers it together with the conversions to and
+// from its base unit, which may be a compound unit like "kg/m/s^2". The
+// dimension defaults to that of the base unit. The symbol of d is also
+// registered as an alias.
+func (r *Registry) Define(d Def) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.define(d, false)
+}
+
+// define is Define, but if rated is set, a unit without base unit of a
+// dimension that has one is registered without conversions, which are left
+// to rates.
+func (r *Registry) define(d Def, rated bool) error {
+    if d.Symbol != "" && !strings.EqualFold(d.Symbol, d.Name) && !containsFold(d.Aliases, d.Symbol) {
+        d.Aliases = append(d.Aliases, d.Symbol)
+    }
+    var dim Dimension
+    if d.Dimension != "" {
+        var err error
+        if dim, err = ParseDimension(d.Dimension); err != nil {
+            return fmt.Errorf("unit %q: %v", d.Name, err)
+        }
+    }
+    if d.Base == "" {
+        if d.Dimension == "" {
+            return fmt.Errorf("unit %q has neither a dimension nor a base unit", d.Name)
+        }
+        b, ok := r.bases[dim.String()]
+        if ok && !rated {
+            return fmt.Errorf("unit %q: dimension %s already has base unit %s", d.Name, dim, b.Name)
+        }
+        u := &Unit{Name: d.Name, Symbol: d.Symbol, Aliases: d.Aliases, Dimension: dim}
+        if err := r.register(u); err != nil {
+            return err
+        }
+        if !ok {
+            r.bases[dim.String()] = u
+        }
+        return nil
+    }
+    base, err := r.resolve(d.Base)
+    if err != nil {
+        return fmt.Errorf("unit %q: base unit: %v", d.Name, err)
+    }
+    if d.Dimension == "" {
+        dim = base.Dimension
+    } else if !dim.Equal(base.Dimension) {
+        return fmt.Errorf("unit %q: dimension %s differs from %s of base unit %s",
+            d.Name, dim, base.Dimension, base.Name)
+    }
+    if d.Factor == "" {
+        return fmt.Errorf("unit %q has no factor", d.Name)
+    }
+    if d.Offset == "" {
+        d.Offset = "0"
+    }
+    exact, err := ParseAffine(string(d.Factor), string(d.Offset))
+    if err != nil {
+        return fmt.Errorf("unit %q: %v", d.Name, err)
+    }
+    if exact.Scale.Sign() == 0 {
+        return fmt.Errorf("unit %q: factor must not be zero", d.Name)
+    }
+    u := &Unit{Name: d.Name, Symbol: d.Symbol, Aliases: d.Aliases, Dimension: dim, IsDelta: base.IsDelta}
+    if err := r.register(u); err != nil {
+        return err
+    }
+    r.link(u, base, exact, 0)
+    if base.Delta != nil {
+        // Like the built-in scales, a temperature scale gets a unit of
+        // differences, which converts without the offset.
+        delta := &Unit{Name: "delta " + u.Name, Symbol: "Δ" + u.Symbol, Aliases: []string{"Δ" + u.Symbol},
+            Dimension: dim, IsDelta: true}
+        if err := r.register(delta); err != nil {
+            return err
+        }
+        u.Delta = delta
+        r.link(delta, base.Delta, &Affine{exact.Scale, new(big.Rat)}, 0)
+    }
+    return nil
+}
+
+func containsFold(list []string, s string) bool {
+    for _, x := range list {
+        if strings.EqualFold(x, s) {
+            return true
+        }
+    }
+    return false
+}
+
+// Load reads unit definitions in JSON from in and registers them in order.
+func (r *Registry) Load(in io.Reader) error {
+    var file struct {
+        Units []Def `json:"units"`
+    }
+    dec := json.NewDecoder(in)
+    dec.DisallowUnknownFields()
+    if err := dec.Decode(&file); err != nil {
+        return err
+    }
+    for _, d := range file.Units {
+        if err := r.Define(d); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// LoadFile is like Load but reads the named file.
+func (r *Registry) LoadFile(filename string) error {
+    f, err := os.Open(filename)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    if err := r.Load(f); err != nil {
+        return fmt.Errorf("%s: %v", filename, err)
+    }
+    return nil
+}
+
+//!-
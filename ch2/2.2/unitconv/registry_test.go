@@ -0,0 +1,87 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package unitconv
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+// units is the units file of the package documentation.
+const units = `{"units": [
+    {"name": "fathom", "symbol": "ftm", "aliases": ["fathoms"],
+     "dimension": "length", "base": "foot", "factor": 6},
+    {"name": "gas mark", "symbol": "GM", "dimension": "temperature",
+     "base": "celsius", "factor": 14, "offset": 121}
+]}`
+
+func loadUnits(t *testing.T) *Registry {
+    r := Builtin()
+    if err := r.Load(strings.NewReader(units)); err != nil {
+        t.Fatal(err)
+    }
+    return r
+}
+
+func TestLoadedTemperature(t *testing.T) {
+    r := loadUnits(t)
+    gm, _ := r.Lookup("GM")
+    if gm.Delta == nil || gm.IsDelta {
+        t.Fatalf("gas mark is not a temperature scale")
+    }
+    for _, name := range []string{"delta celsius", "delta kelvin", "delta gas mark"} {
+        delta, _ := r.Lookup(name)
+        if CheckConvertible(gm, delta) == nil || CheckConvertible(delta, gm) == nil {
+            t.Errorf("gas mark converts to and from %s", name)
+        }
+    }
+    celsius, _ := r.Lookup("celsius")
+    if got, err := r.Convert(4, gm, celsius); err != nil || got != 177 {
+        t.Errorf("4 GM = %v°C, %v; want 177°C", got, err)
+    }
+    deltaC, _ := r.Lookup("Δ°C")
+    if got, err := r.Convert(1, gm.Delta, deltaC); err != nil || got != 14 {
+        t.Errorf("1 ΔGM = %vΔ°C, %v; want 14Δ°C", got, err)
+    }
+    for _, path := range r.PathsFrom(gm, time.Time{}) {
+        if to := path[len(path)-1].To; to.IsDelta {
+            t.Errorf("gas mark converts to %s", to.Name)
+        }
+    }
+    diff, err := Quantity{5, gm}.Sub(Quantity{4, gm})
+    if err != nil || diff.Unit != gm.Delta || diff.Value != 1 {
+        t.Errorf("5 GM - 4 GM = %v, %v; want 1 ΔGM", diff, err)
+    }
+}
+
+func TestCompoundDimension(t *testing.T) {
+    r := Builtin()
+    def := `{"units": [{"name": "widgetgadget", "symbol": "wg", "dimension": "widget*gadget"}]}`
+    if err := r.Load(strings.NewReader(def)); err != nil {
+        t.Fatal(err)
+    }
+    wg2, err := r.Resolve("wg^2")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got, err := r.Convert(3, wg2, wg2); err != nil || got != 3 {
+        t.Errorf("3 wg^2 = %v wg^2, %v; want 3", got, err)
+    }
+    perM, err := r.Resolve("wg/m")
+    if err != nil {
+        t.Fatal(err)
+    }
+    perKm, err := r.Resolve("wg/km")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got, err := r.Convert(1, perKm, perM); err != nil || got != 0.001 {
+        t.Errorf("1 wg/km = %v wg/m, %v; want 0.001", got, err)
+    }
+}
+
+//!-
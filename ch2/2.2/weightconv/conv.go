@@ -8,9 +8,9 @@
 package weightconv
 
 // PToK converts a Pound weight to Kilogram.
-func PToK(p Pound) Kilogram { return Kilogram(p * 0.453592) }
+func PToK(p Pound) Kilogram { return Kilogram(p * 0.45359237) }
 
-// KToP converts a Pound weight to Kilogram.
-func KToP(k Kilogram) Pound { return Pound(k / 0.453592) }
+// KToP converts a Kilogram weight to Pound.
+func KToP(k Kilogram) Pound { return Pound(k / 0.45359237) }
 
 //!-
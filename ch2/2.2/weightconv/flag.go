@@ -0,0 +1,103 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package weightconv
+
+import (
+    "flag"
+    "fmt"
+    "main/metric"
+    "strings"
+)
+
+var (
+    kilogramUnit = Unit{"kilogram", "kg", []string{"kilograms"}, 1}
+    poundUnit    = Unit{"pound", "lb", []string{"lbs", "pounds"}, 0.45359237}
+)
+
+// lookupUnit returns the unit with the given name, symbol or alias, ignoring
+// case, among Kilogram, Pound, the Metric and the Customary units.
+func lookupUnit(name string) (Unit, bool) {
+    units := append([]Unit{kilogramUnit, poundUnit}, Metric()...)
+    for _, u := range append(units, Customary...) {
+        if strings.EqualFold(name, u.Name) || strings.EqualFold(name, u.Symbol) {
+            return u, true
+        }
+        for _, alias := range u.Aliases {
+            if strings.EqualFold(name, alias) {
+                return u, true
+            }
+        }
+    }
+    return Unit{}, false
+}
+
+// parseWeight splits a weight like "3kg" or "12 lb" into its number and its
+// unit.
+func parseWeight(s string) (float64, Unit, error) {
+    value, name, err := metric.ParseQuantity(s)
+    if err != nil {
+        return 0, Unit{}, fmt.Errorf("invalid weight %q: %v, want a number and a unit like 3kg", s, err)
+    }
+    if name == "" {
+        return 0, Unit{}, fmt.Errorf("invalid weight %q: missing unit like kg or lb", s)
+    }
+    u, ok := lookupUnit(name)
+    if !ok {
+        return 0, Unit{}, fmt.Errorf("invalid weight %q: unknown unit %q", s, name)
+    }
+    return value, u, nil
+}
+
+// Set parses a weight with a unit suffix, like "3kg", "12 lb" or "8oz", and
+// converts it to Kilogram. It makes *Kilogram a flag.Value.
+func (k *Kilogram) Set(s string) error {
+    v, u, err := parseWeight(s)
+    if err != nil {
+        return err
+    }
+    if u.Name == poundUnit.Name {
+        *k = PToK(Pound(v))
+    } else {
+        *k = u.ToKilogram(v)
+    }
+    return nil
+}
+
+// Set is like Kilogram.Set but converts to Pound.
+func (p *Pound) Set(s string) error {
+    v, u, err := parseWeight(s)
+    if err != nil {
+        return err
+    }
+    if u.Name == poundUnit.Name {
+        *p = Pound(v)
+    } else {
+        *p = KToP(u.ToKilogram(v))
+    }
+    return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler like Set.
+func (k *Kilogram) UnmarshalText(text []byte) error { return k.Set(string(text)) }
+
+// UnmarshalText implements encoding.TextUnmarshaler like Set.
+func (p *Pound) UnmarshalText(text []byte) error { return p.Set(string(text)) }
+
+// KilogramFlag defines a Kilogram flag with the specified name, default
+// value, and usage, and returns the address of the flag variable. The flag
+// argument must have a number and a unit, e.g., "3kg" or "12lb".
+func KilogramFlag(name string, value Kilogram, usage string) *Kilogram {
+    flag.CommandLine.Var(&value, name, usage)
+    return &value
+}
+
+// PoundFlag is like KilogramFlag for a Pound flag.
+func PoundFlag(name string, value Pound, usage string) *Pound {
+    flag.CommandLine.Var(&value, name, usage)
+    return &value
+}
+
+//!-
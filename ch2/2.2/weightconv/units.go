@@ -0,0 +1,42 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package weightconv
+
+import "main/metric"
+
+// A Unit is a unit of weight other than Pound and Kilogram.
+type Unit struct {
+    Name, Symbol string
+    Aliases      []string
+    Kilograms    float64 // weight of one unit
+}
+
+// ToKilogram converts v in u to Kilogram.
+func (u Unit) ToKilogram(v float64) Kilogram { return Kilogram(v * u.Kilograms) }
+
+// FromKilogram converts a Kilogram weight to u.
+func (u Unit) FromKilogram(k Kilogram) float64 { return float64(k) / u.Kilograms }
+
+// Customary lists the imperial and US customary units of weight, based on
+// the international pound of 0.45359237 kg.
+var Customary = []Unit{
+    {"ounce", "oz", []string{"ounces"}, 0.028349523125},
+    {"stone", "st", []string{"stones"}, 6.35029318},
+    {"short ton", "ton", []string{"short tons", "tons"}, 907.18474},
+    {"long ton", "LT", []string{"long tons"}, 1016.0469088},
+}
+
+// Metric returns the gram, its multiples with SI prefixes other than kilo,
+// and the tonne.
+func Metric() []Unit {
+    units := []Unit{{"gram", "g", []string{"grams"}, 1e-3}}
+    for _, p := range metric.Prefixed("gram", "g", -3, "kilo") {
+        units = append(units, Unit{p.Name, p.Symbol, p.Aliases, p.Factor})
+    }
+    return append(units, Unit{"tonne", "t", []string{"tonnes", "metric ton", "metric tons"}, 1000})
+}
+
+//!-
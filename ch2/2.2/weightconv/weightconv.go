@@ -3,7 +3,7 @@
 
 //!+
 
-// Package weight performs Pound and Kilogram conversions.
+// Package weightconv performs Pound and Kilogram conversions.
 package weightconv
 
 import "fmt"
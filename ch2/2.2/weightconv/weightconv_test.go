@@ -0,0 +1,96 @@
+// Copyright © 2016 Alan A. A. Donovan & Brian W. Kernighan.
+// License: https://creativecommons.org/licenses/by-nc-sa/4.0/
+
+//!+
+
+package weightconv
+
+import (
+    "math"
+    "testing"
+    "testing/quick"
+)
+
+func TestConv(t *testing.T) {
+    // The international pound is exactly 0.45359237 kg.
+    if got := PToK(1); got != 0.45359237 {
+        t.Errorf("PToK(1 lb) = %v, want 0.45359237 kg", got)
+    }
+    if got := KToP(0.45359237); got != 1 {
+        t.Errorf("KToP(0.45359237 kg) = %v, want 1 lb", got)
+    }
+    if got := PToK(2000); got != 907.18474 {
+        t.Errorf("PToK(2000 lb) = %v, want 907.18474 kg", got)
+    }
+}
+
+func TestUnits(t *testing.T) {
+    pounds := map[string]float64{"ounce": 1.0 / 16, "stone": 14, "short ton": 2000, "long ton": 2240}
+    for _, u := range Customary {
+        w, ok := pounds[u.Name]
+        if !ok {
+            t.Errorf("unexpected unit %s", u.Name)
+            continue
+        }
+        if got := KToP(u.ToKilogram(1)); got != Pound(w) {
+            t.Errorf("1 %s = %v, want %g lb", u.Name, got, w)
+        }
+    }
+    grams := map[string]float64{"gram": 1e-3, "milligram": 1e-6, "microgram": 1e-9, "tonne": 1e3}
+    for _, u := range Metric() {
+        if w, ok := grams[u.Name]; ok && u.Kilograms != w {
+            t.Errorf("1 %s = %v kg, want %v kg", u.Name, u.Kilograms, w)
+        }
+    }
+}
+
+func TestRoundTrip(t *testing.T) {
+    f := func(v float64) bool {
+        return math.Abs(float64(KToP(PToK(Pound(v))))-v) <= 1e-15*math.Abs(v)
+    }
+    if err := quick.Check(f, nil); err != nil {
+        t.Error(err)
+    }
+    for _, u := range append(Metric(), Customary...) {
+        g := func(v float64) bool {
+            v = math.Mod(v, 1e12) // no overflow
+            return math.Abs(u.FromKilogram(u.ToKilogram(v))-v) <= 1e-15*math.Abs(v)
+        }
+        if err := quick.Check(g, nil); err != nil {
+            t.Errorf("%s: %v", u.Name, err)
+        }
+    }
+}
+
+func TestSet(t *testing.T) {
+    tests := []struct {
+        s      string
+        kg     Kilogram
+        pounds Pound
+    }{
+        {"3kg", 3, 3 / 0.45359237},
+        {"12 lb", 12 * 0.45359237, 12},
+        {"8oz", 8 * 0.028349523125, 0.5},
+        {"1 stone", 6.35029318, 14},
+        {"5e2 g", 0.5, 0.5 / 0.45359237},
+    }
+    for _, test := range tests {
+        var k Kilogram
+        var p Pound
+        if err := k.Set(test.s); err != nil || math.Abs(float64(k-test.kg)) > 1e-9 {
+            t.Errorf("Kilogram.Set(%q) = %v, %v, want %v", test.s, k, err, test.kg)
+        }
+        if err := p.Set(test.s); err != nil || math.Abs(float64(p-test.pounds)) > 1e-9 {
+            t.Errorf("Pound.Set(%q) = %v, %v, want %v", test.s, p, err, test.pounds)
+        }
+    }
+    for _, s := range []string{"NaN kg", "Inf lb", "1_000kg", "0x10 lb", "2.5E", "1e999 kg", "12", "12 grains"} {
+        var k Kilogram
+        var p Pound
+        if k.Set(s) == nil || p.Set(s) == nil {
+            t.Errorf("Set(%q) succeeded", s)
+        }
+    }
+}
+
+//!-